@@ -47,6 +47,7 @@ const (
 type Command struct {
 	CacheDir        string        `short:"c" help:"Directory used for caching function images and containers." default:"/function-runtime-oci"`
 	Timeout         time.Duration `help:"Maximum time for which the function may run before being killed." default:"30s"`
+	PullTimeout     time.Duration `help:"Maximum time allowed to pull the function's image and create its filesystem." default:"2m"`
 	ImagePullPolicy string        `help:"Whether the image may be pulled from a remote registry." enum:"Always,Never,IfNotPresent" default:"IfNotPresent"`
 	NetworkPolicy   string        `help:"Whether the function may access the network." enum:"Runner,Isolated" default:"Isolated"`
 	MapRootUID      int           `help:"UID that will map to 0 in the function's user namespace. The following 65336 UIDs must be available. Ignored if function-runtime-oci does not have CAP_SETUID and CAP_SETGID." default:"100000"`
@@ -106,7 +107,8 @@ func (c *Command) Run(args *start.Args) error {
 			},
 		},
 		RunFunctionConfig: &v1alpha1.RunFunctionConfig{
-			Timeout: durationpb.New(c.Timeout),
+			Timeout:     durationpb.New(c.Timeout),
+			PullTimeout: durationpb.New(c.PullTimeout),
 			Network: &v1alpha1.NetworkConfig{
 				Policy: networkPolicy(c.NetworkPolicy),
 			},
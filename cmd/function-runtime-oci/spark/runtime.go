@@ -0,0 +1,143 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spark
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+
+	runtime "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/opencontainers/runtime-spec/specs-go/features"
+	"golang.org/x/sys/unix"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+)
+
+const (
+	errDecodeState    = "cannot decode container state"
+	errDecodeFeatures = "cannot decode OCI runtime features"
+)
+
+// RuntimeFeatures decodes the supplied JSON encoded OCI runtime features, per
+// the runtime's features subcommand. Unknown (i.e. empty) features decode to
+// nil, which means the runtime is assumed to support everything.
+func RuntimeFeatures(s string) (*features.Features, error) {
+	if s == "" {
+		return nil, nil
+	}
+	f := &features.Features{}
+	if err := json.Unmarshal([]byte(s), f); err != nil {
+		return nil, errors.Wrap(err, errDecodeFeatures)
+	}
+	return f, nil
+}
+
+// A Runtime runs containers per the OCI runtime lifecycle.
+type Runtime interface {
+	// Create a container from the supplied bundle. The container's init
+	// process inherits the supplied stdio.
+	Create(ctx context.Context, id, bundle string, stdio Stdio) error
+
+	// Start a created container.
+	Start(ctx context.Context, id string) error
+
+	// State of a container.
+	State(ctx context.Context, id string) (*runtime.State, error)
+
+	// Wait for a container's init process to exit.
+	Wait(pid int) (unix.WaitStatus, error)
+
+	// Kill a container by sending its init process the supplied signal.
+	Kill(ctx context.Context, id, signal string) error
+
+	// Delete a container, killing it if it's running.
+	Delete(ctx context.Context, id string) error
+}
+
+// Stdio of a container's init process.
+type Stdio struct {
+	Stdin  *os.File
+	Stdout *os.File
+	Stderr *os.File
+}
+
+// NewCLI returns a Runtime that invokes the supplied crun binary, storing
+// container state under the supplied root directory.
+func NewCLI(binary, root string) *CLI {
+	return &CLI{binary: binary, root: root}
+}
+
+// A CLI runtime invokes crun. Other OCI runtimes with a similar command line
+// interface, like runc and youki, differ in the flags, state output, and
+// features they support, and aren't supported.
+type CLI struct {
+	binary string
+	root   string
+}
+
+// Create a container from the supplied bundle.
+func (r *CLI) Create(ctx context.Context, id, bundle string, stdio Stdio) error {
+	cmd := r.command(ctx, "create", "--bundle", bundle, id)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = stdio.Stdin, stdio.Stdout, stdio.Stderr
+	return cmd.Run()
+}
+
+// Start a created container.
+func (r *CLI) Start(ctx context.Context, id string) error {
+	return r.command(ctx, "start", id).Run()
+}
+
+// State of a container.
+func (r *CLI) State(ctx context.Context, id string) (*runtime.State, error) {
+	out, err := r.command(ctx, "state", id).Output()
+	if err != nil {
+		return nil, err
+	}
+	s := &runtime.State{}
+	return s, errors.Wrap(json.Unmarshal(out, s), errDecodeState)
+}
+
+// Wait for a container's init process to exit. The container's init process
+// is a child of the runtime's create command, which exits once the container
+// is created. The caller must therefore be a subreaper in order to wait.
+func (r *CLI) Wait(pid int) (unix.WaitStatus, error) {
+	for {
+		var ws unix.WaitStatus
+		_, err := unix.Wait4(pid, &ws, 0, nil)
+		if errors.Is(err, unix.EINTR) {
+			continue
+		}
+		return ws, err
+	}
+}
+
+// Kill a container by sending its init process the supplied signal.
+func (r *CLI) Kill(ctx context.Context, id, signal string) error {
+	return r.command(ctx, "kill", id, signal).Run()
+}
+
+// Delete a container, killing it if it's running.
+func (r *CLI) Delete(ctx context.Context, id string) error {
+	return r.command(ctx, "delete", "--force", id).Run()
+}
+
+func (r *CLI) command(ctx context.Context, args ...string) *exec.Cmd {
+	//nolint:gosec // Executing with user-supplied input is intentional.
+	return exec.CommandContext(ctx, r.binary, append([]string{"--root", r.root}, args...)...)
+}
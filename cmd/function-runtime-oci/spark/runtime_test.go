@@ -0,0 +1,122 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spark
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	runtime "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/opencontainers/runtime-spec/specs-go/features"
+)
+
+func TestRuntimeFeatures(t *testing.T) {
+	type want struct {
+		f   *features.Features
+		err bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		s      string
+		want   want
+	}{
+		"Unknown": {
+			reason: "Unknown features should decode to nil, meaning everything is supported.",
+			want:   want{},
+		},
+		"Known": {
+			reason: "Known features should be decoded.",
+			s:      `{"ociVersionMin":"1.0.0","ociVersionMax":"1.1.0"}`,
+			want:   want{f: &features.Features{OCIVersionMin: "1.0.0", OCIVersionMax: "1.1.0"}},
+		},
+		"Invalid": {
+			reason: "Invalid features should return an error.",
+			s:      "wat",
+			want:   want{err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			f, err := RuntimeFeatures(tc.s)
+			got := want{f: f, err: err != nil}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nRuntimeFeatures(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+// fakeCrun records its arguments, and prints state like crun does.
+const fakeCrun = `#!/bin/sh
+echo "$*" >> "$(dirname "$0")/args"
+if [ "$3" = "state" ]; then
+  echo '{"ociVersion":"1.0.0","id":"cool-container","pid":42,"status":"created","bundle":"/bundle","rootfs":"/bundle/rootfs","created":"2023-01-01T00:00:00Z","owner":""}'
+fi
+`
+
+func TestCLI(t *testing.T) {
+	tmp := t.TempDir()
+	crun := filepath.Join(tmp, "crun")
+	if err := os.WriteFile(crun, []byte(fakeCrun), 0700); err != nil { //nolint:gosec // The fake runtime must be executable.
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	r := NewCLI(crun, "/root")
+	if err := r.Create(ctx, "cool-container", "/bundle", Stdio{}); err != nil {
+		t.Fatalf("Create(...): %v", err)
+	}
+	if err := r.Start(ctx, "cool-container"); err != nil {
+		t.Fatalf("Start(...): %v", err)
+	}
+	s, err := r.State(ctx, "cool-container")
+	if err != nil {
+		t.Fatalf("State(...): %v", err)
+	}
+	if err := r.Kill(ctx, "cool-container", "SIGKILL"); err != nil {
+		t.Fatalf("Kill(...): %v", err)
+	}
+	if err := r.Delete(ctx, "cool-container"); err != nil {
+		t.Fatalf("Delete(...): %v", err)
+	}
+
+	wantState := &runtime.State{Version: "1.0.0", ID: "cool-container", Status: runtime.StateCreated, Pid: 42, Bundle: "/bundle"}
+	if diff := cmp.Diff(wantState, s); diff != "" {
+		t.Errorf("State(...): -want, +got:\n%s", diff)
+	}
+
+	b, err := os.ReadFile(filepath.Join(tmp, "args"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantArgs := []string{
+		"--root /root create --bundle /bundle cool-container",
+		"--root /root start cool-container",
+		"--root /root state cool-container",
+		"--root /root kill cool-container SIGKILL",
+		"--root /root delete --force cool-container",
+	}
+	if diff := cmp.Diff(wantArgs, strings.Split(strings.TrimSpace(string(b)), "\n")); diff != "" {
+		t.Errorf("crun: -want arguments, +got:\n%s", diff)
+	}
+}
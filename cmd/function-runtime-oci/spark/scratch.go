@@ -0,0 +1,150 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spark
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+
+	"github.com/crossplane/function-runtime-oci/internal/proto/v1alpha1"
+)
+
+// Error strings.
+const (
+	errParseEphemeralStorage = "cannot parse ephemeral storage limit"
+	errEphemeralStorageZero  = "ephemeral storage limit must be greater than zero"
+	errMkScratchDir          = "cannot make scratch directory"
+	errMountScratch          = "cannot mount scratch tmpfs"
+	errUnmountScratch        = "cannot unmount scratch tmpfs"
+	errRemoveScratchDir      = "cannot remove scratch directory"
+	errMkTmpDir              = "cannot make /tmp directory in root filesystem"
+	errEphemeralStorage      = "function exceeded its ephemeral storage limit"
+)
+
+// How often to check whether a function has filled its scratch space.
+const tmpFSPollInterval = 100 * time.Millisecond
+
+// The path within the cache dir under which each run's scratch space is
+// mounted.
+const scratchRoot = "scratch"
+
+// The path at which scratch space is mounted in the container.
+const scratchMountPath = "/tmp"
+
+// The size of the tmpfs that stores writes to a container's root filesystem,
+// if the ephemeral storage limit is unset. It's the same size as the /tmp the
+// container gets by default.
+const defaultRootFSScratchBytes = 64 << 20 // 64 MiB
+
+// ephemeralStorageLimit returns the ephemeral storage limit of the supplied
+// config in bytes, or zero if it's unset.
+func ephemeralStorageLimit(cfg *v1alpha1.RunFunctionConfig) (int64, error) {
+	l := cfg.GetResources().GetLimits().GetEphemeralStorage()
+	if l == "" {
+		return 0, nil
+	}
+	q, err := resource.ParseQuantity(l)
+	if err != nil {
+		return 0, errors.Wrap(err, errParseEphemeralStorage)
+	}
+	if q.Sign() <= 0 {
+		return 0, errors.New(errEphemeralStorageZero)
+	}
+	return q.Value(), nil
+}
+
+// mountScratch mounts a tmpfs of the supplied size in bytes at the supplied
+// path. It returns a function that unmounts the tmpfs and removes the path.
+func mountScratch(path string, size int64) (func() error, error) {
+	if err := os.MkdirAll(path, 0o700); err != nil {
+		return nil, errors.Wrap(err, errMkScratchDir)
+	}
+	if err := mountScratchTmpFS(path, size); err != nil {
+		_ = os.Remove(path)
+		return nil, errors.Wrap(err, errMountScratch)
+	}
+	return func() error {
+		if err := unmountTmpFS(path); err != nil {
+			return errors.Wrap(err, errUnmountScratch)
+		}
+		return errors.Wrap(os.Remove(path), errRemoveScratchDir)
+	}, nil
+}
+
+// mkTmpDir makes a /tmp directory that anyone may write to in the supplied
+// root filesystem, unless it already has one. A writable root filesystem has
+// no /tmp mount, so its /tmp must exist for the function to write to it.
+func mkTmpDir(rootfs string) error {
+	path := filepath.Join(rootfs, scratchMountPath)
+	if err := os.Mkdir(path, 0o700); err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return nil
+		}
+		return errors.Wrap(err, errMkTmpDir)
+	}
+	return errors.Wrap(os.Chmod(path, 0o777|os.ModeSticky), errMkTmpDir) //nolint:gosec // Anyone may write to /tmp.
+}
+
+// watchTmpFS polls the tmpfs filesystems at the supplied paths at the
+// supplied interval, until the returned function is called. The returned
+// function reports whether any of them were full at any point. A function that
+// fills its scratch space might free it again before it exits, so checking only
+// once it has exited isn't enough.
+func watchTmpFS(interval time.Duration, paths ...string) func() bool {
+	if len(paths) == 0 {
+		return func() bool { return false }
+	}
+	stop := make(chan struct{})
+	full := make(chan bool, 1)
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			if anyTmpFSFull(paths...) {
+				full <- true
+				return
+			}
+			select {
+			case <-stop:
+				full <- anyTmpFSFull(paths...)
+				return
+			case <-t.C:
+			}
+		}
+	}()
+	return func() bool {
+		close(stop)
+		return <-full
+	}
+}
+
+// anyTmpFSFull returns true if any of the tmpfs filesystems at the supplied
+// paths are full. A function that fills its scratch space usually fails with
+// ENOSPC, which we want to report as hitting its ephemeral storage limit.
+func anyTmpFSFull(paths ...string) bool {
+	for _, p := range paths {
+		if tmpFSFull(p) {
+			return true
+		}
+	}
+	return false
+}
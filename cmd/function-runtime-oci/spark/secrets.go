@@ -0,0 +1,89 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spark
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+)
+
+// Error strings.
+const (
+	errMkSecretsDir     = "cannot make secrets directory"
+	errMountSecrets     = "cannot mount secrets tmpfs"
+	errUnmountSecrets   = "cannot unmount secrets tmpfs"
+	errRemoveSecretsDir = "cannot remove secrets directory"
+
+	errFmtInvalidSecretFileName = "invalid secret file name %q"
+	errFmtWriteSecretFile       = "cannot write secret file %q"
+)
+
+// The path within the cache dir under which each run's secrets are written.
+const secretsRoot = "secrets"
+
+// The path at which secret files are mounted in the container.
+const secretsMountPath = "/run/secrets"
+
+// validateSecretFiles returns an error if any of the supplied secret files
+// can't be written to a single directory - e.g. because its name is a path.
+// It never includes the content of a secret file in its error.
+func validateSecretFiles(files map[string][]byte) error {
+	for name := range files {
+		if name == "" || name == "." || name == ".." || strings.ContainsRune(name, filepath.Separator) {
+			return errors.Errorf(errFmtInvalidSecretFileName, name)
+		}
+	}
+	return nil
+}
+
+// writeSecretFiles writes the supplied secret files to a tmpfs mounted at the
+// supplied path, so that they're never written to disk. It returns a function
+// that unmounts the tmpfs and removes the path.
+func writeSecretFiles(path string, files map[string][]byte) (func() error, error) {
+	if err := os.MkdirAll(path, 0o700); err != nil {
+		return nil, errors.Wrap(err, errMkSecretsDir)
+	}
+	if err := mountTmpFS(path); err != nil {
+		_ = os.Remove(path)
+		return nil, errors.Wrap(err, errMountSecrets)
+	}
+	cleanup := func() error {
+		if err := unmountTmpFS(path); err != nil {
+			return errors.Wrap(err, errUnmountSecrets)
+		}
+		return errors.Wrap(os.Remove(path), errRemoveSecretsDir)
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// The container may not run as the user that owns these files.
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(path, name), files[name], 0o444); err != nil {
+			_ = cleanup()
+			return nil, errors.Wrapf(err, errFmtWriteSecretFile, name)
+		}
+	}
+	return cleanup, nil
+}
@@ -21,26 +21,38 @@ package spark
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io"
 	"os"
-	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 	"time"
 
 	"github.com/google/go-containerregistry/pkg/name"
+	ociv1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/uuid"
 	runtime "github.com/opencontainers/runtime-spec/specs-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sys/unix"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
 
 	"github.com/crossplane/crossplane-runtime/pkg/errors"
 
 	"github.com/crossplane/function-runtime-oci/cmd/function-runtime-oci/start"
+	"github.com/crossplane/function-runtime-oci/internal/cgroup"
+	"github.com/crossplane/function-runtime-oci/internal/container"
+	"github.com/crossplane/function-runtime-oci/internal/metrics"
 	"github.com/crossplane/function-runtime-oci/internal/oci"
 	"github.com/crossplane/function-runtime-oci/internal/oci/spec"
 	"github.com/crossplane/function-runtime-oci/internal/oci/store"
 	"github.com/crossplane/function-runtime-oci/internal/oci/store/overlay"
 	"github.com/crossplane/function-runtime-oci/internal/oci/store/uncompressed"
 	"github.com/crossplane/function-runtime-oci/internal/proto/v1alpha1"
+	"github.com/crossplane/function-runtime-oci/internal/tracing"
 )
 
 // Error strings.
@@ -50,6 +62,7 @@ const (
 	errNewBundleStore   = "cannot create OCI runtime bundle store"
 	errNewDigestStore   = "cannot create OCI image digest store"
 	errParseRef         = "cannot parse OCI image reference"
+	errParsePlatform    = "cannot parse OCI image platform"
 	errPull             = "cannot pull OCI image"
 	errBundleFn         = "cannot create OCI runtime bundle"
 	errMkRuntimeRootdir = "cannot make OCI runtime cache"
@@ -59,7 +72,35 @@ const (
 	errWriteResponse    = "cannot write response data to stdout"
 	errCPULimit         = "cannot limit container CPU"
 	errMemoryLimit      = "cannot limit container memory"
+	errPidsLimit        = "cannot limit container pids"
+	errDropCapabilities = "cannot drop container capabilities"
 	errHostNetwork      = "cannot configure container to run in host network namespace"
+	errEnv              = "cannot configure container environment variables"
+	errWritableRootFS   = "cannot make container root filesystem writable"
+	errNoWritableRootFS = "writable root filesystems require overlayfs support, which is unavailable"
+	errSetupTracing     = "cannot set up tracing"
+	errParsePublicKeys  = "cannot parse image verification public keys"
+	errNewVerifiedStore = "cannot create OCI image verification store"
+	errNewVerifier      = "cannot create OCI image verifier"
+	errAdmissionPolicy  = "cannot load OCI image admission policy"
+	errAdmitImage       = "OCI image denied by admission policy"
+	errInvalidConfig    = "invalid RunFunctionConfig"
+	errTimedOut         = "function timed out"
+	errPullTimedOut     = "timed out pulling OCI image"
+	errBundleTimedOut   = "timed out creating OCI runtime bundle"
+	errCancelled        = "function run was cancelled"
+	errProbablyOOM      = "function was killed, probably because it exceeded its memory limit"
+	errOOMKilled        = "function was killed because it exceeded its memory limit"
+	errSubreaper        = "cannot become a subreaper"
+	errPipe             = "cannot create pipe"
+	errCreateContainer  = "cannot create container"
+	errStartContainer   = "cannot start container"
+	errContainerState   = "cannot get container state"
+	errReadStdio        = "cannot read container stdio"
+	errWaitContainer    = "cannot wait for container to exit"
+
+	errFmtContainerStatus = "container has unexpected status %q"
+	errFmtRlimit          = "cannot set container %s"
 )
 
 // The path within the cache dir that the OCI runtime should use for its
@@ -70,36 +111,159 @@ const ociRuntimeRoot = "runtime"
 // the RunFunctionRequest.
 const defaultTimeout = 25 * time.Second
 
+// The time allowed to pull the function's image and create its OCI runtime
+// bundle if none is specified in the RunFunctionRequest.
+const defaultPullTimeout = 2 * time.Minute
+
+// How long the OCI runtime has to exit after it's asked to kill a container,
+// before it's killed too.
+const runtimeStopTimeout = 5 * time.Second
+
+// The time allowed to flush buffered spans before spark exits. The function
+// runner waits for spark to exit, so this bounds how long an unreachable
+// collector can delay a function run.
+const tracingShutdownTimeout = 1 * time.Second
+
 // Command runs a containerized Composition Function.
 type Command struct {
-	CacheDir      string `short:"c" help:"Directory used for caching function images and containers." default:"/function-runtime-oci"`
-	Runtime       string `help:"OCI runtime binary to invoke." default:"crun"`
-	MaxStdioBytes int64  `help:"Maximum size of stdout and stderr for functions." default:"0"`
-	CABundlePath  string `help:"Additional CA bundle to use when fetching function images from registry." env:"CA_BUNDLE_PATH"`
+	CacheDir        string `short:"c" help:"Directory used for caching function images and containers." default:"/function-runtime-oci"`
+	Runtime         string `help:"OCI runtime binary to invoke. Only crun is supported." default:"crun"`
+	RuntimeFeatures string `help:"JSON encoded features of the OCI runtime, per its features subcommand. The runtime is assumed to support all features if empty."`
+	MaxStdioBytes   int64  `help:"Maximum size of stdout and stderr for functions." default:"0"`
+	SeccompProfile  string `help:"Seccomp profile for functions. Either 'default', 'unconfined', or the path to a JSON profile." default:"default"`
+	CABundlePath    string `help:"Additional CA bundle to use when fetching function images from registry." env:"CA_BUNDLE_PATH"`
+	Platform        string `help:"Platform against which to resolve multi-platform images, in os/arch[/variant] form. Defaults to the host's platform."`
+	PublicKeysPath  string `help:"PEM encoded public keys, one of which must have signed a function's image. Images aren't verified if empty."`
+	AdmissionPolicy string `help:"YAML or JSON policy that determines which images may be run. Any image may be run if empty."`
+	ReportFD        int    `help:"File descriptor to which a JSON report of the function run will be written. Disabled if zero." default:"0"`
+	OTLPEndpoint    string `help:"OTLP gRPC endpoint to which traces are exported. Disabled if empty."`
+	OTLPInsecure    bool   `help:"Export traces to the OTLP endpoint without TLS."`
 }
 
 // Run a Composition Function inside an unprivileged user namespace. Reads a
 // protocol buffer serialized RunFunctionRequest from stdin, and writes a
-// protocol buffer serialized RunFunctionResponse to stdout.
-func (c *Command) Run(args *start.Args) error { //nolint:gocyclo // TODO(negz): Refactor some of this out into functions, add tests.
+// protocol buffer serialized RunFunctionResponse to stdout. If the run fails
+// it writes a protocol buffer serialized RunFunctionFailure to stdout instead,
+// and returns an error.
+func (c *Command) Run(args *start.Args) error {
+	f := &v1alpha1.RunFunctionFailure{RunId: uuid.NewString(), Phase: v1alpha1.RunFunctionPhase_RUN_FUNCTION_PHASE_SETUP}
+
+	// The OCI runtime and the container it runs mustn't inherit the report
+	// pipe. The function runner reads our report until the pipe's write end
+	// is closed, which won't happen while any of them hold it open.
+	if c.ReportFD > 0 {
+		unix.CloseOnExec(c.ReportFD)
+	}
+
+	flush, err := c.setupTracing()
+	var rsp *v1alpha1.RunFunctionResponse
+	if err == nil {
+		rsp, err = c.run(args, f)
+	}
+
+	// We flush spans only once we've written our response, so that the
+	// function runner isn't kept waiting on an unreachable collector any
+	// longer than necessary.
+	defer flush()
+	if err == nil {
+		var pb []byte
+		if pb, err = proto.Marshal(rsp); err == nil {
+			_, err = os.Stdout.Write(pb)
+			return errors.Wrap(done(err), errWriteResponse)
+		}
+		err = errors.Wrap(err, errMarshalResponse)
+	}
+
+	// This is best effort. The function runner falls back to our stderr if it
+	// can't read why we failed.
+	f.Message = err.Error()
+	f.Code = int32(code(err))
+	if pb, err := proto.Marshal(f); err == nil {
+		_, _ = os.Stdout.Write(pb)
+	}
+	_, _ = fmt.Fprintln(os.Stderr, err)
+	return done(err)
+}
+
+// done closes stdout and stderr, which tells the function runner that we've
+// written our response. It returns the supplied error.
+func done(err error) error {
+	_ = os.Stdout.Close()
+	_ = os.Stderr.Close()
+	return err
+}
+
+// setupTracing installs a TracerProvider if an OTLP endpoint is configured. It
+// returns a function that flushes buffered spans.
+func (c *Command) setupTracing() (func(), error) {
+	if c.OTLPEndpoint == "" {
+		return func() {}, nil
+	}
+	shutdown, err := tracing.Setup(context.Background(), c.OTLPEndpoint, c.OTLPInsecure)
+	if err != nil {
+		return func() {}, errors.Wrap(err, errSetupTracing)
+	}
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+		defer cancel()
+		_ = shutdown(ctx)
+	}, nil
+}
+
+// run a Composition Function. The supplied failure is updated as the run
+// progresses, so that it describes the run if it fails.
+func (c *Command) run(args *start.Args, f *v1alpha1.RunFunctionFailure) (*v1alpha1.RunFunctionResponse, error) { //nolint:gocyclo // TODO(negz): Refactor some of this out into functions.
 	pb, err := io.ReadAll(os.Stdin)
 	if err != nil {
-		return errors.Wrap(err, errReadRequest)
+		return nil, errors.Wrap(err, errReadRequest)
 	}
 
 	req := &v1alpha1.RunFunctionRequest{}
 	if err := proto.Unmarshal(pb, req); err != nil {
-		return errors.Wrap(err, errUnmarshalRequest)
+		return nil, errors.Wrap(err, errUnmarshalRequest)
+	}
+
+	// Catch invalid configuration before we spend time pulling the image.
+	if _, err := spec.New(FromRunFunctionConfig(req.GetRunFunctionConfig())); err != nil {
+		return nil, withCode(errors.Wrap(err, errInvalidConfig), codes.InvalidArgument)
+	}
+	if err := validateSecretFiles(req.GetRunFunctionConfig().GetSecretFiles()); err != nil {
+		return nil, withCode(errors.Wrap(err, errInvalidConfig), codes.InvalidArgument)
+	}
+	esl, err := ephemeralStorageLimit(req.GetRunFunctionConfig())
+	if err != nil {
+		return nil, withCode(errors.Wrap(err, errInvalidConfig), codes.InvalidArgument)
 	}
 
 	t := req.GetRunFunctionConfig().GetTimeout().AsDuration()
 	if t == 0 {
 		t = defaultTimeout
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), t)
+	pt := req.GetRunFunctionConfig().GetPullTimeout().AsDuration()
+	if pt == 0 {
+		pt = defaultPullTimeout
+	}
+	// The function runner passes us the trace context of this run, if any.
+	ctx := tracing.FromEnviron(context.Background(), os.Environ())
+
+	// The function runner asks us to stop by sending SIGTERM, e.g. when it's
+	// shutting down. Cancelling our context kills the OCI runtime, after which
+	// we clean up the bundle as we would if the function had failed.
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	// A cold pull of a large image can take much longer than the function
+	// takes to run, so pulling the image and creating the bundle have their
+	// own deadline.
+	ictx, cancel := context.WithTimeout(ctx, pt)
 	defer cancel()
 
-	runID := uuid.NewString()
+	// The function runner can't see inside this process, so we report how the
+	// run went for it to record as metrics.
+	report := metrics.NewReport()
+	if c.ReportFD > 0 {
+		defer c.writeReport(report)
+	}
 
 	// We prefer to use an overlayfs bundler where possible. It roughly doubles
 	// the disk space per image because it caches layers as overlay compatible
@@ -108,120 +272,481 @@ func (c *Command) Run(args *start.Args) error { //nolint:gocyclo // TODO(negz):
 	// cached image, because it creates an overlay rootfs. The uncompressed
 	// bundler on the other hand must untar all of a containers layers to create
 	// a new rootfs each time it runs a container.
+	//
+	// The overlay bundler stores writes to the rootfs in memory, so we bound
+	// them by the ephemeral storage limit. The uncompressed bundler would
+	// write them to disk, alongside the extracted rootfs, where we can't bound
+	// them. We don't support a writable rootfs without overlays.
+	writable := req.GetRunFunctionConfig().GetWritableRootFilesystem()
+	ul := int64(defaultRootFSScratchBytes)
+	if esl > 0 {
+		ul = esl
+	}
 	var s store.Bundler = uncompressed.NewBundler(c.CacheDir)
-	if overlay.Supported(c.CacheDir) {
-		s, err = overlay.NewCachingBundler(c.CacheDir)
+	switch {
+	case overlay.Supported(c.CacheDir):
+		s, err = overlay.NewCachingBundler(c.CacheDir, overlay.WithUpperLimit(ul))
+	case writable:
+		return nil, withCode(errors.New(errNoWritableRootFS), codes.FailedPrecondition)
 	}
 	if err != nil {
-		return errors.Wrap(err, errNewBundleStore)
+		return nil, errors.Wrap(err, errNewBundleStore)
 	}
 
 	// This store maps OCI references to their last known digests. We use it to
 	// resolve references when the imagePullPolicy is Never or IfNotPresent.
 	h, err := store.NewDigest(c.CacheDir)
 	if err != nil {
-		return errors.Wrap(err, errNewDigestStore)
+		return nil, errors.Wrap(err, errNewDigestStore)
 	}
 
 	r, err := name.ParseReference(req.GetImage(), name.WithDefaultRegistry(args.Registry))
 	if err != nil {
-		return errors.Wrap(err, errParseRef)
+		return nil, errors.Wrap(err, errParseRef)
+	}
+
+	// Admit the image reference before we pull anything. The policy's size
+	// limits are enforced by the puller.
+	var ap *oci.AdmissionPolicy
+	if c.AdmissionPolicy != "" {
+		ap, err = oci.ReadAdmissionPolicy(c.AdmissionPolicy)
+		if err != nil {
+			return nil, errors.Wrap(err, errAdmissionPolicy)
+		}
+	}
+	if err := ap.AdmitReference(r); err != nil {
+		return nil, withCode(errors.Wrap(err, errAdmitImage), codes.PermissionDenied)
 	}
 
 	opts := []oci.ImageClientOption{FromImagePullConfig(req.GetImagePullConfig())}
 	if c.CABundlePath != "" {
 		rootCA, err := oci.ParseCertificatesFromPath(c.CABundlePath)
 		if err != nil {
-			return errors.Wrap(err, "Cannot parse CA bundle")
+			return nil, errors.Wrap(err, "Cannot parse CA bundle")
 		}
 		opts = append(opts, oci.WithCustomCA(rootCA))
 	}
+	if c.Platform != "" {
+		pl, err := ociv1.ParsePlatform(c.Platform)
+		if err != nil {
+			return nil, errors.Wrap(err, errParsePlatform)
+		}
+		opts = append(opts, oci.WithPlatform(*pl))
+	}
 	// We cache every image we pull to the filesystem. Layers are cached as
 	// uncompressed tarballs. This allows them to be extracted quickly when
 	// using the uncompressed.Bundler, which extracts a new root filesystem for
 	// every container run.
-	p := oci.NewCachingPuller(h, store.NewImage(c.CacheDir), &oci.RemoteClient{})
-	img, err := p.Image(ctx, r, opts...)
+	popts := []oci.CachingPullerOption{oci.WithPullMetrics(report), oci.WithAdmissionPolicy(ap)}
+	if c.PublicKeysPath != "" {
+		keys, err := oci.ParsePublicKeysFromPath(c.PublicKeysPath)
+		if err != nil {
+			return nil, errors.Wrap(err, errParsePublicKeys)
+		}
+		vs, err := store.NewVerified(c.CacheDir)
+		if err != nil {
+			return nil, errors.Wrap(err, errNewVerifiedStore)
+		}
+		v, err := oci.NewSignatureVerifier(keys, vs, &oci.RemoteClient{})
+		if err != nil {
+			return nil, errors.Wrap(err, errNewVerifier)
+		}
+		popts = append(popts, oci.WithVerifier(v))
+	}
+	p := oci.NewCachingPuller(h, store.NewImage(c.CacheDir), &oci.RemoteClient{}, popts...)
+	f.Phase = v1alpha1.RunFunctionPhase_RUN_FUNCTION_PHASE_PULL
+	pctx, done := phase(ictx, report, metrics.PhasePull, attribute.String("image", r.String()))
+	img, err := p.Image(pctx, r, opts...)
+	done(err)
 	if err != nil {
-		return errors.Wrap(err, errPull)
+		if errors.Is(ictx.Err(), context.DeadlineExceeded) {
+			return nil, withCode(errors.Wrap(err, errPullTimedOut), codes.DeadlineExceeded)
+		}
+		if oci.IsVerificationFailed(err) || oci.IsAdmissionDenied(err) {
+			return nil, withCode(errors.Wrap(err, errPull), codes.PermissionDenied)
+		}
+		if req.GetImagePullConfig().GetPullPolicy() == v1alpha1.ImagePullPolicy_IMAGE_PULL_POLICY_NEVER {
+			return nil, withCode(errors.Wrap(err, errPull), codes.FailedPrecondition)
+		}
+		return nil, errors.Wrap(err, errPull)
+	}
+
+	root := filepath.Join(c.CacheDir, ociRuntimeRoot)
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return nil, errors.Wrap(err, errMkRuntimeRootdir)
+	}
+	rt := NewCLI(c.Runtime, root)
+
+	// The function runner asks the OCI runtime which features it supports
+	// when it starts, so we don't have to for each run.
+	ft, err := RuntimeFeatures(c.RuntimeFeatures)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create an OCI runtime bundle for this container run.
-	b, err := s.Bundle(ctx, img, runID, FromRunFunctionConfig(req.GetRunFunctionConfig()))
+	f.Phase = v1alpha1.RunFunctionPhase_RUN_FUNCTION_PHASE_BUNDLE
+	bctx, done := phase(ictx, report, metrics.PhaseBundle)
+	sopts := []spec.Option{spec.WithSeccompProfileName(c.SeccompProfile), FromRunFunctionConfig(req.GetRunFunctionConfig())}
+	if files := req.GetRunFunctionConfig().GetSecretFiles(); len(files) > 0 {
+		path := filepath.Join(c.CacheDir, secretsRoot, f.GetRunId())
+		cleanupSecrets, err := writeSecretFiles(path, files)
+		if err != nil {
+			done(err)
+			return nil, err
+		}
+		// We're in our own mount namespace, so the tmpfs goes away when we
+		// exit even if we can't clean it up.
+		defer cleanupSecrets() //nolint:errcheck // See above.
+		sopts = append(sopts, spec.WithReadOnlyBindMount(path, secretsMountPath))
+	}
+	// A writable rootfs and /tmp share one ephemeral storage budget, so /tmp
+	// is part of the rootfs rather than its own tmpfs.
+	var scratch []string
+	switch {
+	case writable:
+		sopts = append(sopts, spec.WithoutMount(scratchMountPath))
+	case esl > 0:
+		path := filepath.Join(c.CacheDir, scratchRoot, f.GetRunId())
+		cleanupScratch, err := mountScratch(path, esl)
+		if err != nil {
+			done(err)
+			return nil, err
+		}
+		defer cleanupScratch() //nolint:errcheck // Like secrets, this goes away with our mount namespace.
+		sopts = append(sopts, spec.WithScratchMount(path, scratchMountPath))
+		scratch = append(scratch, path)
+	}
+	b, err := s.Bundle(bctx, img, f.GetRunId(), append(sopts, spec.WithFeatures(ft))...)
+	done(err)
 	if err != nil {
-		return errors.Wrap(err, errBundleFn)
+		if errors.Is(ictx.Err(), context.DeadlineExceeded) {
+			return nil, withCode(errors.Wrap(err, errBundleTimedOut), codes.DeadlineExceeded)
+		}
+		return nil, errors.Wrap(err, errBundleFn)
+	}
+	if ob, ok := b.(overlay.Bundle); ok && writable {
+		if err := mkTmpDir(filepath.Join(ob.Path(), store.DirRootFS)); err != nil {
+			_ = b.Cleanup()
+			return nil, err
+		}
+		scratch = append(scratch, ob.ScratchPath())
+	}
+	cleanup := func() error {
+		_, done := phase(ctx, report, metrics.PhaseCleanup)
+		err := b.Cleanup()
+		done(err)
+		return err
 	}
 
-	root := filepath.Join(c.CacheDir, ociRuntimeRoot)
-	if err := os.MkdirAll(root, 0700); err != nil {
-		_ = b.Cleanup()
-		return errors.Wrap(err, errMkRuntimeRootdir)
+	// The function's timeout starts when its container is created.
+	f.Phase = v1alpha1.RunFunctionPhase_RUN_FUNCTION_PHASE_RUN
+	ectx, cancelRun := context.WithTimeout(ctx, t)
+	defer cancelRun()
+	rctx, done := phase(ectx, report, metrics.PhaseRuntime, attribute.String("runtime", filepath.Base(c.Runtime)))
+	u := &v1alpha1.ResourceUsage{}
+	full := watchTmpFS(tmpFSPollInterval, scratch...)
+	stdout, stderr, err := runContainer(rctx, rt, f.GetRunId(), b, bytes.NewReader(req.GetInput()), c.MaxStdioBytes, u)
+	wasFull := full()
+	done(err)
+
+	// We always clean up the bundle, even if the run failed or was cancelled.
+	// Otherwise we'd leak its overlay mounts and directories.
+	if err != nil {
+		setExitStatus(ectx, f, err)
+		container.SetStderrTail(f, stderr)
+		// We stopped watching the scratch space before cleaning up the
+		// bundle, which unmounts its rootfs scratch space.
+		_ = cleanup()
+		if wasFull {
+			return nil, withCode(errors.Wrap(err, errEphemeralStorage), codes.ResourceExhausted)
+		}
+		return nil, runtimeError(ectx, f, req.GetRunFunctionConfig(), err)
 	}
 
-	// TODO(negz): Consider using the OCI runtime's lifecycle management commands
-	// (i.e create, start, and delete) rather than run. This would allow spark
-	// to return without sitting in-between function-runtime-oci and crun. It's also generally
-	// recommended; 'run' is more for testing. In practice though run seems to
-	// work just fine for our use case.
+	f.Phase = v1alpha1.RunFunctionPhase_RUN_FUNCTION_PHASE_CLEANUP
+	if err := cleanup(); err != nil {
+		return nil, errors.Wrap(err, errCleanupBundle)
+	}
 
-	//nolint:gosec // Executing with user-supplied input is intentional.
-	cmd := exec.CommandContext(ctx, c.Runtime, "--root="+root, "run", "--bundle="+b.Path(), runID)
-	cmd.Stdin = bytes.NewReader(req.GetInput())
+	return &v1alpha1.RunFunctionResponse{Output: stdout, Usage: u, TerminationReason: v1alpha1.TerminationReason_TERMINATION_REASON_EXITED}, nil
+}
 
-	stdoutPipe, err := cmd.StdoutPipe()
+// runContainer runs the supplied bundle as a container with the supplied ID,
+// returning its stdout and stderr. The container is killed if the supplied
+// context is done before it exits, and is always deleted. The resources the
+// container used are recorded in the supplied usage once it exits.
+func runContainer(ctx context.Context, rt Runtime, id string, b store.Bundle, stdin io.Reader, maxStdioBytes int64, u *v1alpha1.ResourceUsage) ([]byte, []byte, error) { //nolint:gocyclo // Only slightly over.
+	// The container's init process is a child of the OCI runtime's create
+	// command, which exits once the container is created. We become a
+	// subreaper so that the container is reparented to us, and we can wait
+	// for it to exit.
+	if err := setSubreaper(); err != nil {
+		return nil, nil, errors.Wrap(err, errSubreaper)
+	}
+
+	// The container inherits the OCI runtime's stdio.
+	stdinR, stdinW, err := os.Pipe()
 	if err != nil {
-		_ = b.Cleanup()
-		return errors.Wrap(err, errRuntime)
+		return nil, nil, errors.Wrap(err, errPipe)
 	}
-	stderrPipe, err := cmd.StderrPipe()
+	defer stdinW.Close() //nolint:errcheck // Usually already closed.
+	stdoutR, stdoutW, err := os.Pipe()
 	if err != nil {
-		_ = b.Cleanup()
-		return errors.Wrap(err, errRuntime)
+		return nil, nil, errors.Wrap(err, errPipe)
 	}
-
-	if err := cmd.Start(); err != nil {
-		_ = b.Cleanup()
-		return errors.Wrap(err, errRuntime)
+	defer stdoutR.Close() //nolint:errcheck // Nothing to do if this fails.
+	stderrR, stderrW, err := os.Pipe()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, errPipe)
 	}
+	defer stderrR.Close() //nolint:errcheck // Nothing to do if this fails.
+
+	err = rt.Create(ctx, id, b.Path(), Stdio{Stdin: stdinR, Stdout: stdoutW, Stderr: stderrW})
+
+	// Only the container should hold these now. Otherwise we'd never see EOF.
+	_ = stdinR.Close()
+	_ = stdoutW.Close()
+	_ = stderrW.Close()
+
+	// We delete the container on every exit path, including when we fail
+	// part way through creating it.
+	defer deleteContainer(rt, id)
 
-	stdout, err := io.ReadAll(limitReaderIfNonZero(stdoutPipe, c.MaxStdioBytes))
 	if err != nil {
-		_ = b.Cleanup()
-		return errors.Wrap(err, errRuntime)
+		return nil, nil, errors.Wrap(err, errCreateContainer)
 	}
-	stderr, err := io.ReadAll(limitReaderIfNonZero(stderrPipe, c.MaxStdioBytes))
+
+	s, err := rt.State(ctx, id)
 	if err != nil {
-		_ = b.Cleanup()
-		return errors.Wrap(err, errRuntime)
+		return nil, nil, errors.Wrap(err, errContainerState)
+	}
+	if s.Status != runtime.StateCreated {
+		return nil, nil, errors.Errorf(errFmtContainerStatus, s.Status)
+	}
+
+	// We must find the container's cgroup before it exits, and read its stats
+	// before it's deleted, because the OCI runtime removes the cgroup then.
+	cg := containerCgroup(s.Pid)
+
+	go func() {
+		_, _ = io.Copy(stdinW, stdin)
+		_ = stdinW.Close()
+	}()
+
+	started := time.Now()
+	if err := rt.Start(ctx, id); err != nil {
+		return nil, nil, errors.Wrap(err, errStartContainer)
 	}
 
-	if err := cmd.Wait(); err != nil {
-		var exitErr *exec.ExitError
-		if errors.As(err, &exitErr) {
-			exitErr.Stderr = stderr
+	// Killing the container closes its stdio and ends our wait for it.
+	exited := make(chan struct{})
+	defer close(exited)
+	go func() {
+		select {
+		case <-ctx.Done():
+			killContainer(rt, id, s.Pid)
+		case <-exited:
 		}
-		_ = b.Cleanup()
-		return errors.Wrap(err, errRuntime)
+	}()
+
+	// A container that writes more than maxStdioBytes still runs to
+	// completion; we just discard what it writes past the limit.
+	stdout, stderr, serr := container.ReadStdio(stdoutR, stderrR, maxStdioBytes)
+	if serr != nil && !container.IsStdioLimitExceeded(serr) {
+		return stdout, stderr, errors.Wrap(serr, errReadStdio)
 	}
 
-	if err := b.Cleanup(); err != nil {
-		return errors.Wrap(err, errCleanupBundle)
+	// Only a truncated stdout corrupts the function's output. A truncated
+	// stderr is just truncated.
+	if !container.IsStdoutLimitExceeded(serr) {
+		serr = nil
 	}
 
-	rsp := &v1alpha1.RunFunctionResponse{Output: stdout}
-	pb, err = proto.Marshal(rsp)
+	ws, err := rt.Wait(s.Pid)
+	st := recordUsage(u, cg, time.Since(started))
 	if err != nil {
-		return errors.Wrap(err, errMarshalResponse)
+		return stdout, stderr, errors.Wrap(err, errWaitContainer)
+	}
+	if ws.Signaled() || ws.ExitStatus() != 0 {
+		return stdout, stderr, &exitError{status: ws, oomKilled: st.OOMKills > 0}
 	}
-	_, err = os.Stdout.Write(pb)
-	return errors.Wrap(err, errWriteResponse)
+	return stdout, stderr, serr
+}
+
+// containerCgroup returns the path of the cgroup v2 of the supplied container
+// init process. It returns an empty string if the container doesn't have its
+// own cgroup, for example because the OCI runtime couldn't create one.
+func containerCgroup(pid int) string {
+	cg, err := cgroup.Path(pid)
+	if err != nil {
+		return ""
+	}
+	// We don't want to report our own usage as the container's.
+	if self, err := cgroup.Path(os.Getpid()); err != nil || self == cg {
+		return ""
+	}
+	return cg
+}
+
+// recordUsage records the wall clock duration of a container run and the
+// resource usage of the supplied cgroup, if any, returning the cgroup's stats.
+// It's best effort; a function run shouldn't fail because we can't measure it.
+func recordUsage(u *v1alpha1.ResourceUsage, cg string, wall time.Duration) cgroup.Stats {
+	u.WallClock = durationpb.New(wall)
+	if cg == "" {
+		return cgroup.Stats{}
+	}
+	st, err := cgroup.ReadStats(cg)
+	if err != nil {
+		return cgroup.Stats{}
+	}
+	u.CpuUser = durationpb.New(st.CPUUser)
+	u.CpuSystem = durationpb.New(st.CPUSystem)
+	u.MemoryPeakBytes = st.MemoryPeak
+	u.PidsPeak = st.PidsPeak
+	u.ReadBytes = st.ReadBytes
+	u.WriteBytes = st.WriteBytes
+	return st
+}
+
+// killContainer asks the OCI runtime to kill the supplied container. It kills
+// the container's init process directly if the OCI runtime can't.
+func killContainer(rt Runtime, id string, pid int) {
+	ctx, cancel := context.WithTimeout(context.Background(), runtimeStopTimeout)
+	defer cancel()
+	if err := rt.Kill(ctx, id, "KILL"); err != nil {
+		_ = unix.Kill(pid, unix.SIGKILL)
+	}
+}
+
+// deleteContainer deletes the supplied container, if it exists, killing it if
+// it's still running. It's best effort.
+func deleteContainer(rt Runtime, id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), runtimeStopTimeout)
+	defer cancel()
+	if _, err := rt.State(ctx, id); err != nil {
+		// The container was never created.
+		return
+	}
+	_ = rt.Delete(ctx, id)
+}
+
+// An exitError indicates that a container exited unsuccessfully.
+type exitError struct {
+	status unix.WaitStatus
+
+	// oomKilled is true if the OOM killer killed one of the container's
+	// processes.
+	oomKilled bool
+}
+
+func (e *exitError) Error() string {
+	if e.oomKilled {
+		return "container process killed by the OOM killer"
+	}
+	if e.status.Signaled() {
+		return "container killed by signal: " + e.status.Signal().String()
+	}
+	return fmt.Sprintf("container exited with status %d", e.status.ExitStatus())
+}
+
+// writeReport writes the supplied report to the report file descriptor. It's
+// best effort; a function run shouldn't fail because we can't report on it.
+func (c *Command) writeReport(r *metrics.Report) {
+	f := os.NewFile(uintptr(c.ReportFD), "report")
+	_ = r.Write(f)
+	_ = f.Close()
 }
 
-func limitReaderIfNonZero(r io.Reader, limit int64) io.Reader {
-	if limit == 0 {
-		return r
+// phase starts timing and tracing the supplied phase of the run. The returned
+// function must be called with the phase's error, if any, when it's done.
+func phase(ctx context.Context, r *metrics.Report, name string, attrs ...attribute.KeyValue) (context.Context, func(error)) {
+	timed := r.Time(name)
+	ctx, span := tracing.Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+	return ctx, func(err error) {
+		timed()
+		tracing.End(span, err)
+	}
+}
+
+// A codedError should be returned with a particular gRPC status code.
+type codedError struct {
+	error
+	code codes.Code
+}
+
+func (e codedError) Unwrap() error { return e.error }
+
+func withCode(err error, c codes.Code) error {
+	return codedError{error: err, code: c}
+}
+
+// code returns the gRPC status code that best describes the supplied error.
+func code(err error) codes.Code {
+	var ce codedError
+	switch {
+	case errors.As(err, &ce):
+		return ce.code
+	case oci.IsNotFound(err):
+		return codes.NotFound
+	case oci.IsUnauthorized(err):
+		return codes.Unauthenticated
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	}
+	return codes.Unknown
+}
+
+// runtimeError describes an error returned by the OCI runtime, per the supplied
+// failure, which must already record how the OCI runtime exited.
+func runtimeError(ctx context.Context, f *v1alpha1.RunFunctionFailure, cfg *v1alpha1.RunFunctionConfig, err error) error {
+	switch {
+	case f.GetTerminationReason() == v1alpha1.TerminationReason_TERMINATION_REASON_OOM_KILLED:
+		return withCode(errors.Wrap(err, errOOMKilled), codes.ResourceExhausted)
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return withCode(errors.Wrap(err, errTimedOut), codes.DeadlineExceeded)
+	case errors.Is(ctx.Err(), context.Canceled):
+		return withCode(errors.Wrap(err, errCancelled), codes.Canceled)
+	case container.IsStdoutLimitExceeded(err):
+		// The function's output was truncated, so it's no use to anyone.
+		return withCode(err, codes.ResourceExhausted)
+	case f.GetSignal() == "SIGKILL" && cfg.GetResources().GetLimits().GetMemory() != "":
+		// Nothing else should SIGKILL a function that's limited to a
+		// certain amount of memory, so this is most likely the OOM killer.
+		// We only get here if we couldn't read the container's cgroup.
+		return withCode(errors.Wrap(err, errProbablyOOM), codes.ResourceExhausted)
+	}
+	return errors.Wrap(err, errRuntime)
+}
+
+// setExitStatus records how and why the container exited, per the supplied
+// error and the supplied context of its run, in the supplied failure.
+func setExitStatus(ctx context.Context, f *v1alpha1.RunFunctionFailure, err error) {
+	var exitErr *exitError
+	if !errors.As(err, &exitErr) {
+		return
+	}
+
+	f.TerminationReason = v1alpha1.TerminationReason_TERMINATION_REASON_EXITED
+	f.ExitCode = int32(exitErr.status.ExitStatus())
+	if exitErr.status.Signaled() {
+		f.TerminationReason = v1alpha1.TerminationReason_TERMINATION_REASON_SIGNALED
+		f.ExitCode = -1
+		f.Signal = unix.SignalName(exitErr.status.Signal())
+	}
+
+	// The OOM killer and our own timeout both SIGKILL the container, so we
+	// can't tell them apart by how it exited. The OOM killer records that it
+	// killed a process in the container's cgroup, so we check that first.
+	switch {
+	case exitErr.oomKilled:
+		f.TerminationReason = v1alpha1.TerminationReason_TERMINATION_REASON_OOM_KILLED
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		f.TerminationReason = v1alpha1.TerminationReason_TERMINATION_REASON_TIMED_OUT
 	}
-	return io.LimitReader(r, limit)
 }
 
 // FromImagePullConfig configures an image client with options derived from the
@@ -264,12 +789,58 @@ func FromRunFunctionConfig(cfg *v1alpha1.RunFunctionConfig) spec.Option {
 			}
 		}
 
+		// Unset limits are nil, so we need to check the fields directly.
+		l := cfg.GetResources().GetLimits()
+		if l == nil {
+			l = &v1alpha1.ResourceLimits{}
+		}
+		if l.Pids != nil {
+			if err := spec.WithPidsLimit(l.GetPids())(s); err != nil {
+				return errors.Wrap(err, errPidsLimit)
+			}
+		}
+
+		rlimits := []struct {
+			typ   string
+			limit *uint64
+		}{
+			{typ: spec.RlimitOpenFiles, limit: l.OpenFiles},
+			{typ: spec.RlimitProcesses, limit: l.Processes},
+			{typ: spec.RlimitCoreFileSize, limit: l.CoreFileSize},
+		}
+		for _, rl := range rlimits {
+			if rl.limit == nil {
+				continue
+			}
+			if err := spec.WithRlimit(rl.typ, *rl.limit)(s); err != nil {
+				return errors.Wrapf(err, errFmtRlimit, rl.typ)
+			}
+		}
+
+		if cfg.GetSecurity().GetDropAllCapabilities() {
+			if err := spec.WithoutCapabilities()(s); err != nil {
+				return errors.Wrap(err, errDropCapabilities)
+			}
+		}
+
 		if cfg.GetNetwork().GetPolicy() == v1alpha1.NetworkPolicy_NETWORK_POLICY_RUNNER {
 			if err := spec.WithHostNetwork()(s); err != nil {
 				return errors.Wrap(err, errHostNetwork)
 			}
 		}
 
+		if cfg.GetWritableRootFilesystem() {
+			if err := spec.WithWritableRootFS()(s); err != nil {
+				return errors.Wrap(err, errWritableRootFS)
+			}
+		}
+
+		if env := cfg.GetEnv(); len(env) > 0 {
+			if err := spec.WithEnv(env)(s); err != nil {
+				return errors.Wrap(err, errEnv)
+			}
+		}
+
 		return nil
 	}
 }
@@ -0,0 +1,55 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spark
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// setSubreaper makes this process the subreaper for its descendants, so that
+// they're reparented to it rather than to init when their parent exits.
+func setSubreaper() error {
+	return unix.Prctl(unix.PR_SET_CHILD_SUBREAPER, 1, 0, 0, 0)
+}
+
+// mountTmpFS mounts a tmpfs at the supplied path.
+func mountTmpFS(path string) error {
+	return unix.Mount("tmpfs", path, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, "mode=0755")
+}
+
+// mountScratchTmpFS mounts a tmpfs of the supplied size in bytes at the
+// supplied path. Anyone may write to it, and execute what they write.
+func mountScratchTmpFS(path string, size int64) error {
+	return unix.Mount("tmpfs", path, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, fmt.Sprintf("mode=1777,size=%d", size))
+}
+
+// tmpFSFull returns true if the tmpfs at the supplied path has no free blocks
+// or inodes. It returns false if the path can't be statted.
+func tmpFSFull(path string) bool {
+	st := &unix.Statfs_t{}
+	if err := unix.Statfs(path, st); err != nil {
+		return false
+	}
+	return st.Bavail == 0 || st.Ffree == 0
+}
+
+// unmountTmpFS unmounts the tmpfs at the supplied path.
+func unmountTmpFS(path string) error {
+	return unix.Unmount(path, 0)
+}
@@ -0,0 +1,40 @@
+//go:build !linux
+
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spark
+
+import (
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+)
+
+const errLinuxOnly = "containerized functions are only supported on Linux"
+
+// setSubreaper returns an error on non-Linux.
+func setSubreaper() error { return errors.New(errLinuxOnly) }
+
+// mountTmpFS returns an error on non-Linux.
+func mountTmpFS(_ string) error { return errors.New(errLinuxOnly) }
+
+// mountScratchTmpFS returns an error on non-Linux.
+func mountScratchTmpFS(_ string, _ int64) error { return errors.New(errLinuxOnly) }
+
+// tmpFSFull returns false on non-Linux.
+func tmpFSFull(_ string) bool { return false }
+
+// unmountTmpFS returns an error on non-Linux.
+func unmountTmpFS(_ string) error { return errors.New(errLinuxOnly) }
@@ -0,0 +1,193 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spark
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/function-runtime-oci/internal/container"
+	"github.com/crossplane/function-runtime-oci/internal/proto/v1alpha1"
+)
+
+// A fake OCI runtime. Its containers run the bundle's run.sh script.
+const fakeRuntime = `#!/bin/sh
+root="$2"
+shift 2
+case "$1" in
+create)
+	bundle="$3"
+	dir="$root/$4"
+	mkdir -p "$dir"
+	# Background processes read /dev/null unless their stdin is redirected.
+	exec 3<&0
+	(
+		while [ ! -f "$dir/started" ]; do sleep 0.01; done
+		exec sh "$bundle/run.sh" <&3
+	) &
+	echo $! > "$dir/pid"
+	;;
+state)
+	dir="$root/$2"
+	[ -d "$dir" ] || exit 1
+	pid=$(cat "$dir/pid")
+	status=created
+	if [ -f "$dir/started" ]; then
+		status=stopped
+		kill -0 "$pid" 2>/dev/null && status=running
+	fi
+	printf '{"ociVersion":"1.1.0","id":"%s","status":"%s","pid":%s}' "$2" "$status" "$pid"
+	;;
+start)
+	touch "$root/$2/started"
+	;;
+kill)
+	kill -9 "$(cat "$root/$2/pid")"
+	;;
+delete)
+	kill -9 "$(cat "$root/$3/pid")" 2>/dev/null
+	rm -rf "${root:?}/$3"
+	;;
+esac
+`
+
+type fakeBundle struct{ path string }
+
+func (b fakeBundle) Path() string   { return b.path }
+func (b fakeBundle) Cleanup() error { return os.RemoveAll(b.path) }
+
+func TestRunContainer(t *testing.T) {
+	type want struct {
+		stdout        []byte
+		exitCode      int32
+		signal        string
+		reason        v1alpha1.TerminationReason
+		err           bool
+		limitExceeded bool
+	}
+
+	cases := map[string]struct {
+		reason  string
+		script  string
+		stdin   string
+		limit   int64
+		timeout time.Duration
+		cancel  bool
+		want    want
+	}{
+		"Succeeded": {
+			reason: "A container that exits successfully should return its stdout.",
+			script: "cat",
+			stdin:  "hello",
+			want:   want{stdout: []byte("hello")},
+		},
+		"Failed": {
+			reason: "A container that exits unsuccessfully should return its exit code.",
+			script: "exit 3",
+			want:   want{stdout: []byte{}, exitCode: 3, reason: v1alpha1.TerminationReason_TERMINATION_REASON_EXITED, err: true},
+		},
+		"StderrBeforeStdout": {
+			reason: "A container that fills the stderr pipe before closing stdout shouldn't block.",
+			script: "head -c 1048576 /dev/zero >&2; printf hello",
+			want:   want{stdout: []byte("hello")},
+		},
+		"StdoutExceedsLimit": {
+			reason: "A container that writes too much to stdout should have its stdout truncated, and return an error.",
+			script: "yes | head -c 1048576",
+			limit:  5,
+			want:   want{stdout: []byte("y\ny\ny"), err: true, limitExceeded: true},
+		},
+		"StderrExceedsLimit": {
+			reason: "A container that writes too much to stderr should have its stderr truncated, but still return its stdout.",
+			script: "yes | head -c 1048576 >&2; printf hello",
+			limit:  5,
+			want:   want{stdout: []byte("hello")},
+		},
+		"Killed": {
+			reason: "A container that is killed by a signal should return the signal.",
+			script: "kill -TERM $$",
+			want:   want{stdout: []byte{}, exitCode: -1, signal: "SIGTERM", reason: v1alpha1.TerminationReason_TERMINATION_REASON_SIGNALED, err: true},
+		},
+		"Cancelled": {
+			reason: "A container should be killed if the run is cancelled.",
+			script: "exec sleep 60",
+			cancel: true,
+			want:   want{stdout: []byte{}, exitCode: -1, signal: "SIGKILL", reason: v1alpha1.TerminationReason_TERMINATION_REASON_SIGNALED, err: true},
+		},
+		"TimedOut": {
+			reason:  "A container should be killed if the run times out.",
+			script:  "exec sleep 60",
+			timeout: 200 * time.Millisecond,
+			want:    want{stdout: []byte{}, exitCode: -1, signal: "SIGKILL", reason: v1alpha1.TerminationReason_TERMINATION_REASON_TIMED_OUT, err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			tmp := t.TempDir()
+			rt := filepath.Join(tmp, "runtime")
+			if err := os.WriteFile(rt, []byte(fakeRuntime), 0700); err != nil { //nolint:gosec // The fake runtime must be executable.
+				t.Fatal(err)
+			}
+			bundle := filepath.Join(tmp, "bundle")
+			if err := os.MkdirAll(bundle, 0700); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(bundle, "run.sh"), []byte(tc.script), 0600); err != nil {
+				t.Fatal(err)
+			}
+			root := filepath.Join(tmp, "root")
+
+			ctx, cancel := context.WithCancel(context.Background())
+			if tc.timeout > 0 {
+				ctx, cancel = context.WithTimeout(context.Background(), tc.timeout)
+			}
+			defer cancel()
+
+			// Cancel the run once the container has started.
+			if tc.cancel {
+				go func() {
+					for ctx.Err() == nil {
+						if _, err := os.Stat(filepath.Join(root, "cool-run", "started")); err == nil {
+							cancel()
+							return
+						}
+						time.Sleep(10 * time.Millisecond)
+					}
+				}()
+			}
+
+			stdout, _, err := runContainer(ctx, NewCLI(rt, root), "cool-run", fakeBundle{path: bundle}, bytes.NewReader([]byte(tc.stdin)), tc.limit, &v1alpha1.ResourceUsage{})
+			f := &v1alpha1.RunFunctionFailure{}
+			setExitStatus(ctx, f, err)
+
+			got := want{stdout: stdout, exitCode: f.GetExitCode(), signal: f.GetSignal(), reason: f.GetTerminationReason(), err: err != nil, limitExceeded: container.IsStdioLimitExceeded(err)}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nrunContainer(...): -want, +got:\n%s\nerror: %v", tc.reason, diff, err)
+			}
+			if _, err := os.Stat(filepath.Join(root, "cool-run")); !os.IsNotExist(err) {
+				t.Errorf("\n%s\nrunContainer(...): want container state to be deleted, got stat error %v", tc.reason, err)
+			}
+		})
+	}
+}
@@ -19,20 +19,55 @@ limitations under the License.
 package start
 
 import (
+	"context"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
+	"time"
+
+	ociv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/crossplane/crossplane-runtime/pkg/errors"
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
 
+	"github.com/crossplane/function-runtime-oci/internal/certs"
 	"github.com/crossplane/function-runtime-oci/internal/container"
+	"github.com/crossplane/function-runtime-oci/internal/metrics"
+	"github.com/crossplane/function-runtime-oci/internal/oci"
+	"github.com/crossplane/function-runtime-oci/internal/oci/spec"
+	"github.com/crossplane/function-runtime-oci/internal/tracing"
 )
 
 // Error strings
 const (
-	errListenAndServe = "cannot listen for and serve gRPC API"
+	errListenAndServe       = "cannot listen for and serve gRPC API"
+	errListenAndServeHealth = "cannot listen for and serve HTTP health endpoints"
+	errListenAndServeMetric = "cannot listen for and serve HTTP metrics endpoint"
+	errSetupTracing         = "cannot set up tracing"
+	errTLSKeyPair           = "--tls-cert-file and --tls-key-file must be specified together"
+	errTLSClientCA          = "--tls-client-ca-file requires --tls-cert-file and --tls-key-file"
+	errLoadTLS              = "cannot load TLS certificates"
+	errPeerAllowlistNetwork = "allowed peers can only be enforced when listening on a unix socket"
+	errReadSubUIDs          = "cannot read subordinate UIDs"
+	errReadSubGIDs          = "cannot read subordinate GIDs"
+	errNewIDPool            = "cannot create user namespace ID pool"
+	errIDPoolOverlapsRoot   = "--map-root-uid and --map-root-gid own the image and layer cache, so they mustn't overlap the ID pool"
+	errIDMapMount           = "cannot share the image and layer cache between ID pool ranges"
+	errFmtMaxRunsExceedPool = "--max-concurrent-runs is %d, but the ID pool only has %d ranges"
+	errParsePublicKeys      = "cannot parse image verification public keys"
+	errAdmissionPolicy      = "cannot load image admission policy"
+	errParsePlatform        = "cannot parse image platform"
+	errRuntimeFeatures      = "cannot run functions using the OCI runtime"
 )
 
+// How long to wait for the OCI runtime to report which features it supports.
+const featuresProbeTimeout = 10 * time.Second
+
 // Args contains the default registry used to pull function-runtime-oci
 // containers.
 type Args struct {
@@ -46,6 +81,42 @@ type Command struct {
 	MapRootGID int    `help:"GID that will map to 0 in the function's user namespace. The following 65336 GIDs must be available. Ignored if function-runtime-oci does not have CAP_SETUID and CAP_SETGID." default:"100000"`
 	Network    string `help:"Network on which to listen for gRPC connections." default:"unix"`
 	Address    string `help:"Address at which to listen for gRPC connections." default:"@crossplane/fn/default.sock"`
+	Runtime    string `help:"OCI runtime binary to invoke. Only crun is supported." default:"crun"`
+
+	IDPoolUser string `help:"Allocate each concurrent function run a distinct range of 65536 UIDs and GIDs from this user's subordinate IDs, rather than mapping every run to --map-root-uid and --map-root-gid. All ranges share one image and layer cache, owned by --map-root-uid and --map-root-gid, which mustn't overlap these ranges. Requires idmapped mounts. Ignored if function-runtime-oci does not have CAP_SETUID and CAP_SETGID."`
+	SubUIDFile string `help:"File from which to read the subordinate UIDs of --id-pool-user." default:"/etc/subuid"`
+	SubGIDFile string `help:"File from which to read the subordinate GIDs of --id-pool-user." default:"/etc/subgid"`
+
+	Platform        string `help:"Platform against which to resolve multi-platform function images, in os/arch[/variant] form (e.g. linux/arm64/v8). Defaults to the host's platform."`
+	SeccompProfile  string `help:"Seccomp profile for functions. Either 'default' for the built-in profile, 'unconfined', or the path to a JSON profile in OCI runtime spec format." default:"default"`
+	PublicKeysPath  string `help:"PEM encoded public keys used to verify cosign signatures of function images. Functions whose images aren't signed by one of these keys are rejected. Images aren't verified if empty."`
+	AdmissionPolicy string `help:"YAML or JSON policy that allows or denies function images by registry and repository, and limits their size. Any image is admitted if empty."`
+
+	DropAllCapabilities bool   `help:"Drop all capabilities from every function, regardless of what it requests."`
+	MaxPids             int64  `help:"Maximum pids limit a function may request. Zero means no maximum." default:"0"`
+	MaxOpenFiles        uint64 `help:"Maximum RLIMIT_NOFILE a function may request. Zero means no maximum." default:"0"`
+	MaxProcesses        uint64 `help:"Maximum RLIMIT_NPROC a function may request. Zero means no maximum." default:"0"`
+	MaxCoreFileSize     uint64 `help:"Maximum RLIMIT_CORE, in bytes, a function may request. Zero means no maximum." default:"0"`
+
+	TLSCertFile     string `help:"TLS certificate used to serve the gRPC API. The API is served without TLS if empty. Reloaded when it changes."`
+	TLSKeyFile      string `help:"TLS private key used to serve the gRPC API. Reloaded when it changes."`
+	TLSClientCAFile string `help:"CA bundle used to verify client certificates. Clients must present a certificate (i.e. mTLS) if set. Reloaded when it changes."`
+
+	AllowedPeerUIDs []uint32 `help:"UIDs of processes allowed to run functions over the unix socket. Any process may run functions unless an allowed UID, GID, or PID is specified."`
+	AllowedPeerGIDs []uint32 `help:"GIDs of processes allowed to run functions over the unix socket."`
+	AllowedPeerPIDs []int32  `help:"PIDs of processes allowed to run functions over the unix socket."`
+
+	GracePeriod time.Duration `help:"How long to wait for in-flight function runs to finish when shutting down, before cancelling them." default:"25s"`
+
+	HealthAddress  string `help:"Address at which to serve HTTP /healthz and /readyz endpoints, e.g. :8080. Disabled if empty."`
+	MetricsAddress string `help:"Address at which to serve Prometheus metrics at /metrics, e.g. :8081. Disabled if empty."`
+
+	OTLPEndpoint string `help:"OTLP gRPC endpoint to which traces are exported, e.g. otel-collector:4317. Disabled if empty."`
+	OTLPInsecure bool   `help:"Export traces to the OTLP endpoint without TLS."`
+
+	MaxConcurrentRuns int           `help:"Maximum number of functions that may run at once. Zero means no limit, or one run per range if --id-pool-user is set. Must not exceed the number of ranges if --id-pool-user is set." default:"0"`
+	MaxQueuedRuns     int           `help:"Maximum number of function runs that may wait for a slot once the concurrency limit is reached." default:"64"`
+	QueueTimeout      time.Duration `help:"Maximum time a function run may wait for a slot before failing. Zero means wait until the caller gives up." default:"30s"`
 }
 
 // Run a Composition Function gRPC API.
@@ -60,12 +131,181 @@ func (c *Command) Run(args *Args, log logging.Logger) error {
 		rootGID = c.MapRootGID
 	}
 
-	// TODO(negz): Expose a healthz endpoint and otel metrics.
-	f := container.NewRunner(
+	if c.OTLPEndpoint != "" {
+		shutdown, err := tracing.Setup(context.Background(), c.OTLPEndpoint, c.OTLPInsecure)
+		if err != nil {
+			return errors.Wrap(err, errSetupTracing)
+		}
+		defer shutdown(context.Background()) //nolint:errcheck // Best effort.
+	}
+
+	maxRuns := c.MaxConcurrentRuns
+	var ids *container.IDPool
+	if setuid && c.IDPoolUser != "" {
+		uids, err := container.ReadSubIDRange(c.SubUIDFile, c.IDPoolUser)
+		if err != nil {
+			return errors.Wrap(err, errReadSubUIDs)
+		}
+		gids, err := container.ReadSubIDRange(c.SubGIDFile, c.IDPoolUser)
+		if err != nil {
+			return errors.Wrap(err, errReadSubGIDs)
+		}
+		ids, err = container.NewIDPool(c.IDPoolUser, uids, gids)
+		if err != nil {
+			return errors.Wrap(err, errNewIDPool)
+		}
+
+		// Every range shares an image and layer cache owned by the root UID
+		// and GID, and mounted into the range's cache directory with its IDs
+		// mapped to the range's.
+		owner := container.IDRange{UID: rootUID, GID: rootGID}
+		if ids.Overlaps(owner) {
+			return errors.New(errIDPoolOverlapsRoot)
+		}
+		r, release, err := ids.Acquire()
+		if err != nil {
+			return errors.Wrap(err, errNewIDPool)
+		}
+		err = container.CanIDMapMount(c.CacheDir, owner, r)
+		release()
+		if err != nil {
+			return errors.Wrap(err, errIDMapMount)
+		}
+		// Each run needs its own ID range, so no more than one run per range
+		// may run at once. Runs wait in the queue for a free range, rather
+		// than failing.
+		if maxRuns > ids.Size() {
+			return errors.Errorf(errFmtMaxRunsExceedPool, maxRuns, ids.Size())
+		}
+		if maxRuns <= 0 {
+			log.Info("Limiting concurrent function runs to the number of ID pool ranges.", "max-concurrent-runs", ids.Size())
+			maxRuns = ids.Size()
+		}
+	}
+
+	// spark parses the platform, keys, and admission policy for each run,
+	// but we'd rather find out they're invalid now.
+	if c.Platform != "" {
+		if _, err := ociv1.ParsePlatform(c.Platform); err != nil {
+			return errors.Wrap(err, errParsePlatform)
+		}
+	}
+	if c.PublicKeysPath != "" {
+		if _, err := oci.ParsePublicKeysFromPath(c.PublicKeysPath); err != nil {
+			return errors.Wrap(err, errParsePublicKeys)
+		}
+	}
+	if c.AdmissionPolicy != "" {
+		if _, err := oci.ReadAdmissionPolicy(c.AdmissionPolicy); err != nil {
+			return errors.Wrap(err, errAdmissionPolicy)
+		}
+	}
+
+	// spark needs to know which features the OCI runtime supports in order to
+	// build each function's runtime bundle. They won't change while we run.
+	pctx, cancel := context.WithTimeout(context.Background(), featuresProbeTimeout)
+	ft, err := container.ProbeRuntimeFeatures(pctx, c.Runtime)
+	cancel()
+	if err != nil {
+		log.Info("Cannot determine which features the OCI runtime supports. Assuming it supports all of them.", "runtime", c.Runtime, "error", err)
+	}
+
+	// Every function run would fail if the runtime can't apply the seccomp
+	// profile or namespaces we ask for. We only fall back to running without
+	// seccomp if we were asked to use the default profile.
+	seccomp := c.SeccompProfile
+	if _, err := spec.New(spec.WithSeccompProfileName(seccomp), spec.WithFeatures(ft)); err != nil {
+		if seccomp != "" && seccomp != spec.SeccompProfileDefault {
+			return errors.Wrap(err, errRuntimeFeatures)
+		}
+		if _, err := spec.New(spec.WithoutSeccomp(), spec.WithFeatures(ft)); err != nil {
+			return errors.Wrap(err, errRuntimeFeatures)
+		}
+		log.Info("The OCI runtime cannot apply the default seccomp profile. Functions will run without seccomp.", "runtime", c.Runtime, "error", err)
+		seccomp = spec.SeccompProfileUnconfined
+	}
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+
+	o := []container.RunnerOption{
 		container.SetUID(setuid),
 		container.MapToRoot(rootUID, rootGID),
+		container.WithIDPool(ids),
 		container.WithCacheDir(filepath.Clean(c.CacheDir)),
+		container.WithRuntime(c.Runtime),
+		container.WithRuntimeFeatures(ft),
+		container.WithSeccompProfile(seccomp),
+		container.WithPlatform(c.Platform),
+		container.WithPublicKeys(c.PublicKeysPath),
+		container.WithAdmissionPolicy(c.AdmissionPolicy),
+		container.WithPolicy(container.Policy{
+			DropAllCapabilities: c.DropAllCapabilities,
+			MaxPids:             c.MaxPids,
+			MaxOpenFiles:        c.MaxOpenFiles,
+			MaxProcesses:        c.MaxProcesses,
+			MaxCoreFileSize:     c.MaxCoreFileSize,
+		}),
 		container.WithLogger(log),
-		container.WithRegistry(args.Registry))
-	return errors.Wrap(f.ListenAndServe(c.Network, c.Address), errListenAndServe)
+		container.WithMetrics(metrics.New(reg)),
+		container.WithRegistry(args.Registry),
+		container.WithOTLPEndpoint(c.OTLPEndpoint, c.OTLPInsecure),
+		container.WithMaxConcurrentRuns(maxRuns),
+		container.WithMaxQueuedRuns(c.MaxQueuedRuns),
+		container.WithQueueTimeout(c.QueueTimeout),
+		container.WithGracePeriod(c.GracePeriod),
+	}
+
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return errors.New(errTLSKeyPair)
+	}
+	if c.TLSClientCAFile != "" && c.TLSCertFile == "" {
+		return errors.New(errTLSClientCA)
+	}
+	if c.TLSCertFile != "" {
+		rl, err := certs.NewReloader(c.TLSCertFile, c.TLSKeyFile, c.TLSClientCAFile)
+		if err != nil {
+			return errors.Wrap(err, errLoadTLS)
+		}
+		o = append(o, container.WithTLSConfig(rl.TLSConfig()))
+	}
+
+	if len(c.AllowedPeerUIDs)+len(c.AllowedPeerGIDs)+len(c.AllowedPeerPIDs) > 0 {
+		if c.Network != "unix" {
+			return errors.New(errPeerAllowlistNetwork)
+		}
+		o = append(o, container.WithPeerAllowlist(&container.PeerAllowlist{UIDs: c.AllowedPeerUIDs, GIDs: c.AllowedPeerGIDs, PIDs: c.AllowedPeerPIDs}))
+	}
+
+	f := container.NewRunner(o...)
+
+	// Stop accepting function runs and drain in-flight runs when asked to
+	// shut down, e.g. when our pod is deleted.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	errs := make(chan error, 3)
+	if c.HealthAddress != "" {
+		go func() {
+			log.Debug("Serving health endpoints", "address", c.HealthAddress)
+			srv := &http.Server{Addr: c.HealthAddress, Handler: f.HealthHandler(), ReadHeaderTimeout: 10 * time.Second}
+			errs <- errors.Wrap(srv.ListenAndServe(), errListenAndServeHealth)
+		}()
+	}
+	if c.MetricsAddress != "" {
+		go func() {
+			log.Debug("Serving metrics", "address", c.MetricsAddress)
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+			srv := &http.Server{Addr: c.MetricsAddress, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+			errs <- errors.Wrap(srv.ListenAndServe(), errListenAndServeMetric)
+		}()
+	}
+	go func() {
+		errs <- errors.Wrap(f.ListenAndServe(ctx, c.Network, c.Address), errListenAndServe)
+	}()
+
+	// All servers run until they fail, or until the gRPC server has drained
+	// after being asked to shut down, so return the first error.
+	return <-errs
 }
@@ -0,0 +1,159 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package certs loads TLS certificates used to serve the function runner's
+// gRPC API.
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+)
+
+// Error strings.
+const (
+	errLoadKeyPair   = "cannot load TLS certificate and key"
+	errReadClientCA  = "cannot read client CA bundle"
+	errParseClientCA = "cannot parse any certificates from client CA bundle"
+	errStatFile      = "cannot stat certificate file"
+)
+
+// A Reloader loads a TLS server certificate and key, and optionally a CA bundle
+// used to verify client certificates, from files on disk. It reloads them when
+// the files change, e.g. when a mounted Kubernetes Secret is updated.
+type Reloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+
+	mx      sync.RWMutex
+	cert    *tls.Certificate
+	ca      *x509.CertPool
+	modTime map[string]time.Time
+}
+
+// NewReloader returns a Reloader that serves the supplied certificate and key.
+// Clients must present a certificate signed by the supplied CA bundle, unless
+// it is empty.
+func NewReloader(certFile, keyFile, caFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile, caFile: caFile}
+	mt, err := r.modTimes()
+	if err != nil {
+		return nil, err
+	}
+	return r, r.load(mt)
+}
+
+// TLSConfig returns a TLS configuration that uses the Reloader's certificates.
+// Files are checked for changes each time a client connects.
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(_ *tls.ClientHelloInfo) (*tls.Config, error) {
+			// If we can't reload the files we keep serving the last good
+			// certificates. The files may be partially updated.
+			_ = r.Reload()
+			return r.config(), nil
+		},
+	}
+}
+
+// Reload the certificate, key, and CA bundle if any of their files have
+// changed since they were last loaded.
+func (r *Reloader) Reload() error {
+	mt, err := r.modTimes()
+	if err != nil {
+		return err
+	}
+
+	r.mx.RLock()
+	changed := false
+	for f, t := range mt {
+		if !t.Equal(r.modTime[f]) {
+			changed = true
+		}
+	}
+	r.mx.RUnlock()
+
+	if !changed {
+		return nil
+	}
+	return r.load(mt)
+}
+
+func (r *Reloader) config() *tls.Config {
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+
+	cfg := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{*r.cert},
+	}
+	if r.ca != nil {
+		cfg.ClientCAs = r.ca
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg
+}
+
+func (r *Reloader) load(mt map[string]time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return errors.Wrap(err, errLoadKeyPair)
+	}
+
+	var ca *x509.CertPool
+	if r.caFile != "" {
+		pem, err := os.ReadFile(filepath.Clean(r.caFile))
+		if err != nil {
+			return errors.Wrap(err, errReadClientCA)
+		}
+		ca = x509.NewCertPool()
+		if !ca.AppendCertsFromPEM(pem) {
+			return errors.New(errParseClientCA)
+		}
+	}
+
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	r.cert = &cert
+	r.ca = ca
+	r.modTime = mt
+	return nil
+}
+
+func (r *Reloader) modTimes() (map[string]time.Time, error) {
+	mt := make(map[string]time.Time, 3)
+	for _, f := range []string{r.certFile, r.keyFile, r.caFile} {
+		if f == "" {
+			continue
+		}
+		// Stat follows symlinks, so this notices when Kubernetes atomically
+		// swaps the ..data symlink of a mounted Secret.
+		fi, err := os.Stat(f)
+		if err != nil {
+			return nil, errors.Wrap(err, errStatFile)
+		}
+		mt[f] = fi.ModTime()
+	}
+	return mt, nil
+}
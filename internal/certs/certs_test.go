@@ -0,0 +1,123 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// writeCert writes a new self-signed certificate and key with the supplied
+// common name to the supplied files.
+func writeCert(t *testing.T, certFile, keyFile, cn string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kder, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: kder}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func servedCN(t *testing.T, r *Reloader) string {
+	t.Helper()
+	cfg, err := r.TLSConfig().GetConfigForClient(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatalf("GetConfigForClient(...): %v", err)
+	}
+	c, err := x509.ParseCertificate(cfg.Certificates[0].Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c.Subject.CommonName
+}
+
+func TestReloader(t *testing.T) {
+	tmp := t.TempDir()
+	certFile := filepath.Join(tmp, "tls.crt")
+	keyFile := filepath.Join(tmp, "tls.key")
+
+	writeCert(t, certFile, keyFile, "first")
+	r, err := NewReloader(certFile, keyFile, certFile)
+	if err != nil {
+		t.Fatalf("NewReloader(...): %v", err)
+	}
+
+	if diff := cmp.Diff("first", servedCN(t, r)); diff != "" {
+		t.Errorf("TLSConfig(): -want served certificate, +got:\n%s", diff)
+	}
+	cfg, _ := r.TLSConfig().GetConfigForClient(&tls.ClientHelloInfo{})
+	if diff := cmp.Diff(tls.RequireAndVerifyClientCert, cfg.ClientAuth); diff != "" {
+		t.Errorf("TLSConfig(): -want client auth, +got:\n%s", diff)
+	}
+
+	// Make sure the new files look modified, even on filesystems with coarse
+	// modification times.
+	writeCert(t, certFile, keyFile, "second")
+	later := time.Now().Add(time.Minute)
+	for _, f := range []string{certFile, keyFile} {
+		if err := os.Chtimes(f, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if diff := cmp.Diff("second", servedCN(t, r)); diff != "" {
+		t.Errorf("TLSConfig(): -want reloaded certificate, +got:\n%s", diff)
+	}
+
+	// We should keep serving the last good certificate if we can't reload.
+	if err := os.WriteFile(keyFile, []byte("broken"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("second", servedCN(t, r)); diff != "" {
+		t.Errorf("TLSConfig(): -want last good certificate, +got:\n%s", diff)
+	}
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cgroup reads the resource usage of processes from cgroup v2.
+package cgroup
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+)
+
+// Error strings.
+const (
+	errOpenProcCgroup  = "cannot open /proc/[pid]/cgroup"
+	errReadProcCgroup  = "cannot read /proc/[pid]/cgroup"
+	errNoUnifiedCgroup = "process is not in a cgroup v2 hierarchy"
+
+	errFmtReadFile  = "cannot read %s"
+	errFmtParseFile = "cannot parse %s"
+)
+
+// Root is where the cgroup v2 hierarchy is mounted.
+const Root = "/sys/fs/cgroup"
+
+// Path returns the absolute path of the cgroup v2 to which the supplied process
+// belongs.
+func Path(pid int) (string, error) {
+	f, err := os.Open(filepath.Join("/proc", strconv.Itoa(pid), "cgroup"))
+	if err != nil {
+		return "", errors.Wrap(err, errOpenProcCgroup)
+	}
+	defer f.Close() //nolint:errcheck // Only open for reading.
+
+	p, err := ParseProcCgroup(f)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(Root, p), nil
+}
+
+// ParseProcCgroup parses the supplied /proc/[pid]/cgroup file, returning the
+// path of the process's cgroup v2 relative to the root of the hierarchy.
+func ParseProcCgroup(r io.Reader) (string, error) {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		// cgroup v2 entries look like '0::/path'. cgroup v1 entries have a
+		// non-zero hierarchy ID and a list of controllers.
+		if p, ok := strings.CutPrefix(s.Text(), "0::"); ok {
+			return p, nil
+		}
+	}
+	if err := s.Err(); err != nil {
+		return "", errors.Wrap(err, errReadProcCgroup)
+	}
+	return "", errors.New(errNoUnifiedCgroup)
+}
+
+// Stats describe the resources used by the processes in a cgroup. Stats that
+// the kernel doesn't report are zero.
+type Stats struct {
+	CPUUser    time.Duration
+	CPUSystem  time.Duration
+	MemoryPeak uint64
+	PidsPeak   uint64
+	ReadBytes  uint64
+	WriteBytes uint64
+
+	// OOMKills is the number of processes in the cgroup that were killed by
+	// the OOM killer.
+	OOMKills uint64
+}
+
+// ReadStats reads the stats of the cgroup at the supplied path. Files that
+// don't exist (e.g. memory.peak before Linux 5.19) are ignored.
+func ReadStats(path string) (Stats, error) {
+	st := Stats{}
+
+	cpu, err := readKeyedFile(filepath.Join(path, "cpu.stat"))
+	if err != nil {
+		return Stats{}, err
+	}
+	st.CPUUser = time.Duration(cpu["user_usec"]) * time.Microsecond
+	st.CPUSystem = time.Duration(cpu["system_usec"]) * time.Microsecond
+
+	mem, err := readKeyedFile(filepath.Join(path, "memory.events"))
+	if err != nil {
+		return Stats{}, err
+	}
+	st.OOMKills = mem["oom_kill"]
+
+	if st.MemoryPeak, err = readUint(filepath.Join(path, "memory.peak")); err != nil {
+		return Stats{}, err
+	}
+	if st.PidsPeak, err = readUint(filepath.Join(path, "pids.peak")); err != nil {
+		return Stats{}, err
+	}
+
+	b, err := os.ReadFile(filepath.Join(path, "io.stat"))
+	if err != nil && !os.IsNotExist(err) {
+		return Stats{}, errors.Wrapf(err, errFmtReadFile, "io.stat")
+	}
+	// Each line describes a device, e.g. '8:0 rbytes=1 wbytes=2 rios=3 ...'.
+	for _, line := range strings.Split(string(b), "\n") {
+		for _, field := range strings.Fields(line) {
+			k, v, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			n, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				return Stats{}, errors.Wrapf(err, errFmtParseFile, "io.stat")
+			}
+			switch k {
+			case "rbytes":
+				st.ReadBytes += n
+			case "wbytes":
+				st.WriteBytes += n
+			}
+		}
+	}
+
+	return st, nil
+}
+
+// readKeyedFile reads a flat keyed cgroup file, e.g. 'user_usec 100'. It
+// returns an empty map if the file doesn't exist.
+func readKeyedFile(path string) (map[string]uint64, error) {
+	b, err := os.ReadFile(path) //nolint:gosec // Reading a variable path is intentional.
+	if os.IsNotExist(err) {
+		return map[string]uint64{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, errFmtReadFile, filepath.Base(path))
+	}
+	out := map[string]uint64{}
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		k, v, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, errFmtParseFile, filepath.Base(path))
+		}
+		out[k] = n
+	}
+	return out, nil
+}
+
+// readUint reads a cgroup file containing a single unsigned integer. It
+// returns zero if the file doesn't exist.
+func readUint(path string) (uint64, error) {
+	b, err := os.ReadFile(path) //nolint:gosec // Reading a variable path is intentional.
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.Wrapf(err, errFmtReadFile, filepath.Base(path))
+	}
+	n, err := strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+	return n, errors.Wrapf(err, errFmtParseFile, filepath.Base(path))
+}
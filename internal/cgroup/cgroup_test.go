@@ -0,0 +1,153 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cgroup
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+func TestParseProcCgroup(t *testing.T) {
+	type want struct {
+		path string
+		err  error
+	}
+
+	cases := map[string]struct {
+		reason string
+		file   string
+		want   want
+	}{
+		"Unified": {
+			reason: "We should return the path of a cgroup v2.",
+			file:   "0::/system.slice/cool.scope\n",
+			want: want{
+				path: "/system.slice/cool.scope",
+			},
+		},
+		"Hybrid": {
+			reason: "We should ignore cgroup v1 hierarchies.",
+			file:   "12:pids:/cool\n1:name=systemd:/cool\n0::/cool\n",
+			want: want{
+				path: "/cool",
+			},
+		},
+		"LegacyOnly": {
+			reason: "We should return an error if the process isn't in a cgroup v2.",
+			file:   "12:pids:/cool\n1:name=systemd:/cool\n",
+			want: want{
+				err: errors.New(errNoUnifiedCgroup),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			path, err := ParseProcCgroup(strings.NewReader(tc.file))
+			if diff := cmp.Diff(tc.want.path, path); diff != "" {
+				t.Errorf("\n%s\nParseProcCgroup(...): -want path, +got path:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nParseProcCgroup(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestReadStats(t *testing.T) {
+	type want struct {
+		stats Stats
+		err   error
+	}
+
+	cases := map[string]struct {
+		reason string
+		files  map[string]string
+		want   want
+	}{
+		"AllStats": {
+			reason: "We should read all supported stats.",
+			files: map[string]string{
+				"cpu.stat":      "usage_usec 300\nuser_usec 100\nsystem_usec 200\n",
+				"memory.peak":   "4096\n",
+				"pids.peak":     "3\n",
+				"memory.events": "low 0\nhigh 0\nmax 4\noom 1\noom_kill 1\noom_group_kill 0\n",
+				"io.stat":       "8:0 rbytes=10 wbytes=20 rios=1 wios=2\n8:16 rbytes=1 wbytes=2 rios=1 wios=1\n",
+			},
+			want: want{
+				stats: Stats{
+					CPUUser:    100 * time.Microsecond,
+					CPUSystem:  200 * time.Microsecond,
+					MemoryPeak: 4096,
+					PidsPeak:   3,
+					ReadBytes:  11,
+					WriteBytes: 22,
+					OOMKills:   1,
+				},
+			},
+		},
+		"MissingFiles": {
+			reason: "We should return zero stats for files that don't exist.",
+			files: map[string]string{
+				"cpu.stat": "user_usec 100\nsystem_usec 200\n",
+			},
+			want: want{
+				stats: Stats{
+					CPUUser:   100 * time.Microsecond,
+					CPUSystem: 200 * time.Microsecond,
+				},
+			},
+		},
+		"MalformedFile": {
+			reason: "We should return an error if a file can't be parsed.",
+			files: map[string]string{
+				"pids.peak": "max\n",
+			},
+			want: want{
+				err: cmpopts.AnyError,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			for f, data := range tc.files {
+				if err := os.WriteFile(filepath.Join(dir, f), []byte(data), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			st, err := ReadStats(dir)
+			if diff := cmp.Diff(tc.want.stats, st); diff != "" {
+				t.Errorf("\n%s\nReadStats(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nReadStats(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
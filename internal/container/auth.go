@@ -0,0 +1,133 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package container
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/crossplane/function-runtime-oci/internal/proto/v1alpha1"
+)
+
+// Error strings.
+const (
+	errNoPeerCredentials = "cannot determine the credentials of the calling process"
+	errFmtPeerDenied     = "calling process (uid %d, gid %d, pid %d) is not allowed to run functions"
+)
+
+// PeerCredentials identify the process at the other end of a unix socket.
+type PeerCredentials struct {
+	PID int32
+	UID uint32
+	GID uint32
+}
+
+// A PeerAllowlist specifies which processes may call the Runner over a unix
+// socket. A process is allowed if its UID, GID, or PID is in the allowlist.
+type PeerAllowlist struct {
+	UIDs []uint32
+	GIDs []uint32
+	PIDs []int32
+}
+
+// Allows returns true if the supplied peer credentials are allowed.
+func (a PeerAllowlist) Allows(c PeerCredentials) bool {
+	for _, uid := range a.UIDs {
+		if c.UID == uid {
+			return true
+		}
+	}
+	for _, gid := range a.GIDs {
+		if c.GID == gid {
+			return true
+		}
+	}
+	for _, pid := range a.PIDs {
+		if c.PID == pid {
+			return true
+		}
+	}
+	return false
+}
+
+// A peerCredentialsAddr is the remote address of a unix socket connection,
+// annotated with the credentials of the process at the other end.
+type peerCredentialsAddr struct {
+	net.Addr
+	creds PeerCredentials
+}
+
+// A peerCredentialsConn is a connection whose remote address is annotated with
+// the credentials of the process at the other end.
+type peerCredentialsConn struct {
+	net.Conn
+	addr peerCredentialsAddr
+}
+
+func (c *peerCredentialsConn) RemoteAddr() net.Addr { return c.addr }
+
+// A peerCredentialsListener reads the credentials of the process at the other
+// end of each unix socket connection it accepts. gRPC exposes them to
+// interceptors via the peer's address.
+type peerCredentialsListener struct {
+	net.Listener
+}
+
+func (l *peerCredentialsListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	uc, ok := c.(*net.UnixConn)
+	if !ok {
+		return c, nil
+	}
+	creds, err := GetPeerCredentials(uc)
+	if err != nil {
+		// The authorization interceptor will deny calls from this peer.
+		return c, nil
+	}
+	return &peerCredentialsConn{Conn: c, addr: peerCredentialsAddr{Addr: c.RemoteAddr(), creds: creds}}, nil
+}
+
+// authorizePeer is a gRPC interceptor that only allows calls from processes in
+// the Runner's peer allowlist. Health checks are always allowed.
+func (r *Runner) authorizePeer(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if !strings.HasPrefix(info.FullMethod, "/"+v1alpha1.ContainerizedFunctionRunnerService_ServiceDesc.ServiceName+"/") {
+		return handler(ctx, req)
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.PermissionDenied, errNoPeerCredentials)
+	}
+	a, ok := p.Addr.(peerCredentialsAddr)
+	if !ok {
+		return nil, status.Error(codes.PermissionDenied, errNoPeerCredentials)
+	}
+	if !r.peers.Allows(a.creds) {
+		r.log.Debug("Denied function run", "uid", a.creds.UID, "gid", a.creds.GID, "pid", a.creds.PID)
+		return nil, status.Errorf(codes.PermissionDenied, errFmtPeerDenied, a.creds.UID, a.creds.GID, a.creds.PID)
+	}
+	return handler(ctx, req)
+}
@@ -0,0 +1,58 @@
+//go:build linux
+
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package container
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPeerCredentialsListener(t *testing.T) {
+	l, err := net.Listen("unix", filepath.Join(t.TempDir(), "fn.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lis := &peerCredentialsListener{Listener: l}
+	defer lis.Close() //nolint:errcheck // Only used in tests.
+
+	c, err := net.Dial("unix", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close() //nolint:errcheck // Only used in tests.
+
+	sc, err := lis.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Close() //nolint:errcheck // Only used in tests.
+
+	a, ok := sc.RemoteAddr().(peerCredentialsAddr)
+	if !ok {
+		t.Fatalf("Accept(): want remote address annotated with peer credentials, got %T", sc.RemoteAddr())
+	}
+	want := PeerCredentials{PID: int32(os.Getpid()), UID: uint32(os.Getuid()), GID: uint32(os.Getgid())}
+	if diff := cmp.Diff(want, a.creds); diff != "" {
+		t.Errorf("Accept(): -want peer credentials, +got:\n%s", diff)
+	}
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package container
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/crossplane/function-runtime-oci/internal/proto/v1alpha1"
+)
+
+func TestAuthorizePeer(t *testing.T) {
+	runFunction := "/" + v1alpha1.ContainerizedFunctionRunnerService_ServiceDesc.ServiceName + "/RunFunction"
+	unix := &net.UnixAddr{Name: "@crossplane/fn/default.sock", Net: "unix"}
+
+	type args struct {
+		peer   *peer.Peer
+		method string
+	}
+
+	cases := map[string]struct {
+		reason string
+		peers  *PeerAllowlist
+		args   args
+		want   codes.Code
+	}{
+		"AllowedUID": {
+			reason: "A process with an allowed UID should be allowed.",
+			peers:  &PeerAllowlist{UIDs: []uint32{2000}},
+			args: args{
+				peer:   &peer.Peer{Addr: peerCredentialsAddr{Addr: unix, creds: PeerCredentials{UID: 2000, GID: 3000, PID: 42}}},
+				method: runFunction,
+			},
+			want: codes.OK,
+		},
+		"AllowedGID": {
+			reason: "A process with an allowed GID should be allowed.",
+			peers:  &PeerAllowlist{UIDs: []uint32{0}, GIDs: []uint32{3000}},
+			args: args{
+				peer:   &peer.Peer{Addr: peerCredentialsAddr{Addr: unix, creds: PeerCredentials{UID: 2000, GID: 3000, PID: 42}}},
+				method: runFunction,
+			},
+			want: codes.OK,
+		},
+		"DeniedPeer": {
+			reason: "A process that isn't in the allowlist should be denied.",
+			peers:  &PeerAllowlist{UIDs: []uint32{0}, PIDs: []int32{1}},
+			args: args{
+				peer:   &peer.Peer{Addr: peerCredentialsAddr{Addr: unix, creds: PeerCredentials{UID: 2000, GID: 3000, PID: 42}}},
+				method: runFunction,
+			},
+			want: codes.PermissionDenied,
+		},
+		"NoPeerCredentials": {
+			reason: "A process whose credentials are unknown (e.g. because it connected over TCP) should be denied.",
+			peers:  &PeerAllowlist{UIDs: []uint32{0}},
+			args: args{
+				peer:   &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1234}},
+				method: runFunction,
+			},
+			want: codes.PermissionDenied,
+		},
+		"HealthCheck": {
+			reason: "Health checks should always be allowed.",
+			peers:  &PeerAllowlist{UIDs: []uint32{0}},
+			args: args{
+				peer:   &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1234}},
+				method: "/" + healthpb.Health_ServiceDesc.ServiceName + "/Check",
+			},
+			want: codes.OK,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			r := NewRunner(WithPeerAllowlist(tc.peers))
+			ctx := peer.NewContext(context.Background(), tc.args.peer)
+			handler := func(_ context.Context, _ any) (any, error) { return nil, nil }
+
+			_, err := r.authorizePeer(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tc.args.method}, handler)
+			if diff := cmp.Diff(tc.want, status.Code(err)); diff != "" {
+				t.Errorf("\n%s\nauthorizePeer(...): -want code, +got code:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
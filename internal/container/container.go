@@ -17,15 +17,26 @@ limitations under the License.
 package container
 
 import (
+	"context"
+	"crypto/tls"
 	"io"
 	"net"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/opencontainers/runtime-spec/specs-go/features"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 
 	"github.com/crossplane/crossplane-runtime/pkg/errors"
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
 
+	"github.com/crossplane/function-runtime-oci/internal/metrics"
 	"github.com/crossplane/function-runtime-oci/internal/proto/v1alpha1"
+	"github.com/crossplane/function-runtime-oci/internal/tracing"
 )
 
 // Error strings.
@@ -34,20 +45,58 @@ const (
 	errServe  = "cannot serve gRPC API"
 )
 
-const defaultCacheDir = "/function-runtime-oci"
+const (
+	defaultCacheDir    = "/function-runtime-oci"
+	defaultRuntime     = "crun"
+	defaultGracePeriod = 25 * time.Second
+)
 
 // A Runner runs a Composition Function packaged as an OCI image by
 // extracting it and running it as a 'rootless' container.
 type Runner struct {
 	v1alpha1.UnimplementedContainerizedFunctionRunnerServiceServer
 
-	log logging.Logger
+	log     logging.Logger
+	metrics *metrics.Metrics
+
+	rootUID   int
+	rootGID   int
+	setuid    bool // Specifically, CAP_SETUID and CAP_SETGID.
+	ids       *IDPool
+	cache     string
+	registry  string
+	runtime   string
+	features  *features.Features
+	seccomp   string
+	keys      string
+	platform  string
+	admission string
+	policy    Policy
+
+	otlpEndpoint string
+	otlpInsecure bool
+
+	tls   *tls.Config
+	peers *PeerAllowlist
+
+	maxRuns      int
+	maxQueued    int
+	queueTimeout time.Duration
+	runs         *Limiter
 
-	rootUID  int
-	rootGID  int
-	setuid   bool // Specifically, CAP_SETUID and CAP_SETGID.
-	cache    string
-	registry string
+	gracePeriod time.Duration
+	draining    atomic.Bool
+	inFlight    sync.WaitGroup
+
+	// Creating a user namespace means running a process, so readiness checks
+	// use the result of the most recent attempt rather than trying each time.
+	// It's refreshed only when the gRPC health service re-evaluates readiness.
+	userns      func() error
+	usernsCheck atomic.Pointer[usernsCheck]
+
+	// run is used in place of runFunction if set. It allows function runs to
+	// be faked in tests.
+	run func(ctx context.Context, req *v1alpha1.RunFunctionRequest) (*v1alpha1.RunFunctionResponse, error)
 }
 
 // A RunnerOption configures a new Runner.
@@ -71,6 +120,16 @@ func SetUID(s bool) RunnerOption {
 	}
 }
 
+// WithIDPool configures the Runner to allocate each function run a distinct
+// range of UIDs and GIDs from the supplied pool, rather than mapping every run
+// to the same range starting at the root UID and GID. It has no effect unless
+// the Runner can SetUID.
+func WithIDPool(p *IDPool) RunnerOption {
+	return func(r *Runner) {
+		r.ids = p
+	}
+}
+
 // WithCacheDir specifies the directory used for caching function images and
 // containers.
 func WithCacheDir(d string) RunnerOption {
@@ -87,6 +146,119 @@ func WithRegistry(dr string) RunnerOption {
 	}
 }
 
+// WithRuntime specifies the OCI runtime binary that spark should invoke to run
+// functions. Only crun is supported.
+func WithRuntime(path string) RunnerOption {
+	return func(r *Runner) {
+		r.runtime = path
+	}
+}
+
+// WithRuntimeFeatures specifies the features of the OCI runtime, per
+// ProbeRuntimeFeatures. spark assumes the runtime supports all features if
+// none are specified.
+func WithRuntimeFeatures(f *features.Features) RunnerOption {
+	return func(r *Runner) {
+		r.features = f
+	}
+}
+
+// WithSeccompProfile specifies the seccomp profile that spark should apply to
+// functions. It may be "default", "unconfined", or the path to a JSON profile.
+// The built-in default profile is used if none is specified.
+func WithSeccompProfile(p string) RunnerOption {
+	return func(r *Runner) {
+		r.seccomp = p
+	}
+}
+
+// WithPlatform specifies the platform, in os/arch[/variant] form, against which
+// spark should resolve multi-platform function images. Images are resolved
+// against the host's platform if none is specified.
+func WithPlatform(p string) RunnerOption {
+	return func(r *Runner) {
+		r.platform = p
+	}
+}
+
+// WithPublicKeys specifies a file of PEM encoded public keys, one of which
+// must have signed the image of any function that is run. Images aren't
+// verified if no file is specified.
+func WithPublicKeys(path string) RunnerOption {
+	return func(r *Runner) {
+		r.keys = path
+	}
+}
+
+// WithAdmissionPolicy specifies a YAML or JSON file containing a policy that
+// determines which function images may be run. Any image may be run if no file
+// is specified.
+func WithAdmissionPolicy(path string) RunnerOption {
+	return func(r *Runner) {
+		r.admission = path
+	}
+}
+
+// WithPolicy specifies a policy that constrains how functions are run. Function
+// runs that ask for more than the policy allows are rejected.
+func WithPolicy(p Policy) RunnerOption {
+	return func(r *Runner) {
+		r.policy = p
+	}
+}
+
+// WithMaxConcurrentRuns specifies how many functions may run at once. Runs
+// beyond this limit wait in a queue. Zero (the default) means no limit.
+func WithMaxConcurrentRuns(n int) RunnerOption {
+	return func(r *Runner) {
+		r.maxRuns = n
+	}
+}
+
+// WithMaxQueuedRuns specifies how many function runs may wait for a slot when
+// the maximum number of concurrent runs are in flight. Runs that arrive when
+// the queue is full fail with a ResourceExhausted gRPC status.
+func WithMaxQueuedRuns(n int) RunnerOption {
+	return func(r *Runner) {
+		r.maxQueued = n
+	}
+}
+
+// WithQueueTimeout specifies how long a function run may wait in the queue for
+// a slot before it fails with a ResourceExhausted gRPC status. Zero means runs
+// wait until the caller gives up.
+func WithQueueTimeout(t time.Duration) RunnerOption {
+	return func(r *Runner) {
+		r.queueTimeout = t
+	}
+}
+
+// WithTLSConfig configures the Runner to serve its gRPC API over TLS. The API
+// is served without transport security by default.
+func WithTLSConfig(cfg *tls.Config) RunnerOption {
+	return func(r *Runner) {
+		r.tls = cfg
+	}
+}
+
+// WithPeerAllowlist configures the Runner to only run functions for processes
+// in the supplied allowlist, identified by their unix socket peer credentials.
+// Processes that don't connect via a unix socket are denied. All processes
+// are allowed by default.
+func WithPeerAllowlist(a *PeerAllowlist) RunnerOption {
+	return func(r *Runner) {
+		r.peers = a
+	}
+}
+
+// WithGracePeriod specifies how long the Runner waits for in-flight function
+// runs to finish when it's shutting down, before cancelling them.
+func WithGracePeriod(t time.Duration) RunnerOption {
+	return func(r *Runner) {
+		r.gracePeriod = t
+	}
+}
+
 // WithLogger configures which logger the container runner should use. Logging
 // is disabled by default.
 func WithLogger(l logging.Logger) RunnerOption {
@@ -95,29 +267,105 @@ func WithLogger(l logging.Logger) RunnerOption {
 	}
 }
 
+// WithMetrics configures how the container runner should record metrics.
+// Metrics are not recorded by default.
+func WithMetrics(m *metrics.Metrics) RunnerOption {
+	return func(r *Runner) {
+		r.metrics = m
+	}
+}
+
+// WithOTLPEndpoint configures spark to export traces to the supplied OTLP gRPC
+// endpoint. Traces are not exported by default.
+func WithOTLPEndpoint(endpoint string, insecure bool) RunnerOption {
+	return func(r *Runner) {
+		r.otlpEndpoint = endpoint
+		r.otlpInsecure = insecure
+	}
+}
+
 // NewRunner returns a new Runner that runs functions as rootless
 // containers.
 func NewRunner(o ...RunnerOption) *Runner {
-	r := &Runner{cache: defaultCacheDir, log: logging.NewNopLogger()}
+	r := &Runner{cache: defaultCacheDir, runtime: defaultRuntime, gracePeriod: defaultGracePeriod, log: logging.NewNopLogger(), userns: CanCreateUserNamespace}
 	for _, fn := range o {
 		fn(r)
 	}
+	r.runs = NewLimiter(r.maxRuns, r.maxQueued, r.queueTimeout)
 
 	return r
 }
 
-// ListenAndServe gRPC connections at the supplied address.
-func (r *Runner) ListenAndServe(network, address string) error {
+// ListenAndServe gRPC connections at the supplied address until the supplied
+// context is done. The standard gRPC health service is served alongside the
+// function runner service.
+//
+// Once the context is done the Runner stops accepting new calls and waits up
+// to its grace period for in-flight function runs to finish. Any runs still in
+// flight after the grace period are cancelled, which kills their containers.
+// ListenAndServe returns once they've been cleaned up.
+func (r *Runner) ListenAndServe(ctx context.Context, network, address string) error {
 	r.log.Debug("Listening", "network", network, "address", address)
 	lis, err := net.Listen(network, address)
 	if err != nil {
 		return errors.Wrap(err, errListen)
 	}
 
-	// TODO(negz): Limit concurrent function runs?
-	srv := grpc.NewServer()
+	interceptors := []grpc.UnaryServerInterceptor{tracing.UnaryServerInterceptor()}
+	if r.peers != nil {
+		lis = &peerCredentialsListener{Listener: lis}
+		interceptors = append(interceptors, r.authorizePeer)
+	}
+
+	opts := []grpc.ServerOption{grpc.ChainUnaryInterceptor(interceptors...)}
+	if r.tls != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(r.tls)))
+	}
+
+	srv := grpc.NewServer(opts...)
 	v1alpha1.RegisterContainerizedFunctionRunnerServiceServer(srv, r)
-	return errors.Wrap(srv.Serve(lis), errServe)
+
+	hs := health.NewServer()
+	healthpb.RegisterHealthServer(srv, hs)
+
+	go r.watchHealth(ctx, hs)
+
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.Serve(lis)
+	}()
+
+	select {
+	case err := <-served:
+		return errors.Wrap(err, errServe)
+	case <-ctx.Done():
+	}
+
+	r.log.Debug("Shutting down", "grace-period", r.gracePeriod)
+	r.draining.Store(true)
+
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+
+	t := time.NewTimer(r.gracePeriod)
+	defer t.Stop()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-t.C:
+	}
+
+	// Stop cancels the context of all in-flight calls, but doesn't wait for
+	// their handlers to return. We wait for the cancelled function runs so
+	// that they have a chance to clean up after themselves.
+	r.log.Debug("Grace period expired; cancelling in-flight function runs")
+	srv.Stop()
+	r.inFlight.Wait()
+	return nil
 }
 
 // Stdio can be used to read and write a command's standard I/O.
@@ -19,20 +19,29 @@ limitations under the License.
 package container
 
 import (
-	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
-	"io"
+	"net"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"syscall"
+	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sys/unix"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
 	"kernel.org/pub/linux/libs/security/libcap/cap"
 
 	"github.com/crossplane/crossplane-runtime/pkg/errors"
 
+	"github.com/crossplane/function-runtime-oci/internal/metrics"
 	"github.com/crossplane/function-runtime-oci/internal/proto/v1alpha1"
+	"github.com/crossplane/function-runtime-oci/internal/tracing"
 )
 
 // NOTE(negz): Technically _all_ of the containerized Composition Functions
@@ -47,11 +56,15 @@ const (
 	errCreateStdioPipes  = "cannot create stdio pipes"
 	errStartSpark        = "cannot start " + spark
 	errCloseStdin        = "cannot close stdin pipe"
-	errReadStdout        = "cannot read from stdout pipe"
-	errReadStderr        = "cannot read from stderr pipe"
+	errReadStdio         = "cannot read from stdio pipes"
 	errMarshalRequest    = "cannot marshal RunFunctionRequest for " + spark
 	errWriteRequest      = "cannot write RunFunctionRequest to " + spark + " stdin"
 	errUnmarshalResponse = "cannot unmarshal RunFunctionRequest from " + spark + " stdout"
+	errCreateReportPipe  = "cannot create report pipe"
+	errPeerCredentials   = "cannot get peer credentials"
+	errMkIDCacheDir      = "cannot make cache directory for user namespace ID range"
+	errMountSharedCache  = "cannot mount shared image and layer cache for user namespace ID range"
+	errEncodeFeatures    = "cannot encode OCI runtime features for " + spark
 )
 
 // How many UIDs and GIDs to map from the parent to the child user namespace, if
@@ -62,9 +75,26 @@ const (
 	MaxStdioBytes     = 100 << 20 // 100 MB
 )
 
+// spark's stdout is a marshalled RunFunctionResponse that wraps the function's
+// stdout, which spark limits to MaxStdioBytes. We allow for the overhead of
+// the message that wraps it.
+const sparkStdioOverheadBytes = 1 << 10 // 1 KB
+
 // The subcommand of function-runtime-oci to invoke - i.e. "function-runtime-oci spark <source> <bundle>"
 const spark = "spark"
 
+// The file descriptor from which spark's run report is read. ExtraFiles start
+// at 3, after stdin, stdout, and stderr.
+const reportFD = 3
+
+// How long we wait to read spark's run report after it exits. spark writes its
+// report before it exits, so it should already be waiting in the pipe.
+const reportReadTimeout = 1 * time.Second
+
+// How long spark has to kill its container and clean up after being asked to
+// stop, before it's killed.
+const sparkStopTimeout = 10 * time.Second
+
 // HasCapSetUID returns true if this process has CAP_SETUID.
 func HasCapSetUID() bool {
 	pc := cap.GetProc()
@@ -79,10 +109,75 @@ func HasCapSetGID() bool {
 	return setgid
 }
 
+// CanCreateUserNamespace returns an error if this process cannot create a new
+// user namespace, which is required to run functions. It tests this by running
+// function-runtime-oci --version in a new user namespace.
+func CanCreateUserNamespace() error {
+	cmd := exec.Command(os.Args[0], "--version") //nolint:gosec // We're intentionally executing with variable input.
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags:  syscall.CLONE_NEWUSER,
+		UidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}},
+		GidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}},
+	}
+	return cmd.Run()
+}
+
+// GetPeerCredentials returns the credentials of the process at the other end
+// of the supplied unix socket connection.
+func GetPeerCredentials(c *net.UnixConn) (PeerCredentials, error) {
+	rc, err := c.SyscallConn()
+	if err != nil {
+		return PeerCredentials{}, errors.Wrap(err, errPeerCredentials)
+	}
+	var cred *unix.Ucred
+	var serr error
+	if err := rc.Control(func(fd uintptr) {
+		cred, serr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	}); err != nil {
+		return PeerCredentials{}, errors.Wrap(err, errPeerCredentials)
+	}
+	if serr != nil {
+		return PeerCredentials{}, errors.Wrap(serr, errPeerCredentials)
+	}
+	return PeerCredentials{PID: cred.Pid, UID: cred.Uid, GID: cred.Gid}, nil
+}
+
 // RunFunction runs a function as a rootless OCI container. Functions that
 // return non-zero, or that cannot be executed in the first place (e.g. because
 // they cannot be fetched from the registry) will return an error.
 func (r *Runner) RunFunction(ctx context.Context, req *v1alpha1.RunFunctionRequest) (*v1alpha1.RunFunctionResponse, error) {
+	r.inFlight.Add(1)
+	defer r.inFlight.Done()
+
+	// We check the policy before queueing, so that a request we'll never run
+	// doesn't wait for a slot.
+	cfg, err := r.policy.Apply(req.GetRunFunctionConfig())
+	if err != nil {
+		r.log.Debug("Function run denied by policy", "image", req.GetImage(), "error", err)
+		return nil, err
+	}
+	req = proto.Clone(req).(*v1alpha1.RunFunctionRequest)
+	req.RunFunctionConfig = cfg
+
+	release, err := r.runs.Acquire(ctx)
+	if err != nil {
+		r.log.Debug("Cannot acquire function run slot", "image", req.Image, "error", err)
+		return nil, err
+	}
+	defer release()
+
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("image", req.GetImage()))
+	finished := r.metrics.RunStarted(req.GetImage())
+	run := r.runFunction
+	if r.run != nil {
+		run = r.run
+	}
+	rsp, err := run(ctx, req)
+	finished(status.Code(err).String())
+	return rsp, err
+}
+
+func (r *Runner) runFunction(ctx context.Context, req *v1alpha1.RunFunctionRequest) (*v1alpha1.RunFunctionResponse, error) { //nolint:gocyclo // Only slightly over.
 	r.log.Debug("Running function", "image", req.Image)
 
 	/*
@@ -98,12 +193,79 @@ func (r *Runner) RunFunction(ctx context.Context, req *v1alpha1.RunFunctionReque
 		runtime bundle, then executes an OCI runtime in order to actually
 		execute the function.
 	*/
-	cmd := exec.CommandContext(ctx, os.Args[0], spark, "--cache-dir="+r.cache, "--registry="+r.registry, //nolint:gosec // We're intentionally executing with variable input.
-		fmt.Sprintf("--max-stdio-bytes=%d", MaxStdioBytes))
+	uid, gid, cache := r.rootUID, r.rootGID, r.cache
+	if r.setuid && r.ids != nil {
+		ids, release, err := r.ids.Acquire()
+		if err != nil {
+			r.log.Debug("Cannot acquire user namespace ID range", "image", req.GetImage(), "error", err)
+			return nil, err
+		}
+		defer release()
+		uid, gid = ids.UID, ids.GID
+
+		// Each range has its own cache directory, for what's specific to a
+		// run - e.g. its bundle, scratch space and secrets.
+		cache, err = idRangeCacheDir(r.cache, ids)
+		if err != nil {
+			return nil, errors.Wrap(err, errMkIDCacheDir)
+		}
+
+		// spark caches images and layers as root in its user namespace, so
+		// what it caches would be owned by the range it ran in, and no other
+		// range could use it. Instead all ranges share one image and layer
+		// cache, owned by the root UID and GID. We mount it into the range's
+		// cache directory, mapping the owner's IDs to the range's.
+		owner := IDRange{UID: r.rootUID, GID: r.rootGID}
+		if err := mkSharedCacheDirs(r.cache, owner); err != nil {
+			return nil, err
+		}
+		unmount, err := mountSharedCache(r.cache, cache, owner, ids)
+		if err != nil {
+			return nil, errors.Wrap(err, errMountSharedCache)
+		}
+		defer func() {
+			if err := unmount(); err != nil {
+				r.log.Debug("Cannot unmount shared cache", "image", req.GetImage(), "error", err)
+			}
+		}()
+	}
+
+	cmd := exec.CommandContext(ctx, os.Args[0], spark, "--cache-dir="+cache, "--registry="+r.registry, "--runtime="+r.runtime, //nolint:gosec // We're intentionally executing with variable input.
+		fmt.Sprintf("--max-stdio-bytes=%d", MaxStdioBytes), fmt.Sprintf("--report-fd=%d", reportFD))
+	if r.features != nil {
+		ft, err := json.Marshal(r.features)
+		if err != nil {
+			return nil, errors.Wrap(err, errEncodeFeatures)
+		}
+		cmd.Args = append(cmd.Args, "--runtime-features="+string(ft))
+	}
+	if r.seccomp != "" {
+		cmd.Args = append(cmd.Args, "--seccomp-profile="+r.seccomp)
+	}
+	if r.platform != "" {
+		cmd.Args = append(cmd.Args, "--platform="+r.platform)
+	}
+	if r.keys != "" {
+		cmd.Args = append(cmd.Args, "--public-keys-path="+r.keys)
+	}
+	if r.admission != "" {
+		cmd.Args = append(cmd.Args, "--admission-policy="+r.admission)
+	}
+	if r.otlpEndpoint != "" {
+		cmd.Args = append(cmd.Args, "--otlp-endpoint="+r.otlpEndpoint, fmt.Sprintf("--otlp-insecure=%t", r.otlpInsecure))
+	}
+
+	// Ask spark to stop rather than killing it when the call is cancelled, so
+	// that it has a chance to kill its container and clean up its bundle.
+	cmd.Cancel = func() error { return cmd.Process.Signal(syscall.SIGTERM) }
+	cmd.WaitDelay = sparkStopTimeout
+
+	// spark continues the trace of this function run, if any.
+	cmd.Env = append(os.Environ(), tracing.Environ(ctx)...)
 	cmd.SysProcAttr = &syscall.SysProcAttr{
 		Cloneflags:  syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS,
-		UidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: r.rootUID, Size: 1}},
-		GidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: r.rootGID, Size: 1}},
+		UidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: uid, Size: 1}},
+		GidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: gid, Size: 1}},
 	}
 
 	// When we have CAP_SETUID and CAP_SETGID (i.e. typically when root), we can
@@ -111,8 +273,8 @@ func (r *Runner) RunFunction(ctx context.Context, req *v1alpha1.RunFunctionReque
 	// its parent. We can also drop privileges (in the parent user namespace) by
 	// running spark as root in the user namespace.
 	if r.setuid {
-		cmd.SysProcAttr.UidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: r.rootUID, Size: UserNamespaceUIDs}}
-		cmd.SysProcAttr.GidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: r.rootGID, Size: UserNamespaceGIDs}}
+		cmd.SysProcAttr.UidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: uid, Size: UserNamespaceUIDs}}
+		cmd.SysProcAttr.GidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: gid, Size: UserNamespaceGIDs}}
 		cmd.SysProcAttr.GidMappingsEnableSetgroups = true
 
 		/*
@@ -138,16 +300,31 @@ func (r *Runner) RunFunction(ctx context.Context, req *v1alpha1.RunFunctionReque
 		cmd.SysProcAttr.Credential = &syscall.Credential{Uid: 0, Gid: 0}
 	}
 
-	stdio, err := StdioPipes(cmd, r.rootUID, r.rootGID)
+	stdio, err := StdioPipes(cmd, uid, gid)
 	if err != nil {
 		return nil, errors.Wrap(err, errCreateStdioPipes)
 	}
 
+	// spark writes a report of the run to this pipe, which we use to record
+	// metrics.
+	rr, rw, err := os.Pipe()
+	if err != nil {
+		return nil, errors.Wrap(err, errCreateReportPipe)
+	}
+	defer rr.Close() //nolint:errcheck // Only open for reading.
+	cmd.ExtraFiles = []*os.File{rw}
+
 	b, err := proto.Marshal(req)
 	if err != nil {
+		_ = rw.Close()
 		return nil, errors.Wrap(err, errMarshalRequest)
 	}
-	if err := cmd.Start(); err != nil {
+	err = cmd.Start()
+
+	// Only spark should hold the write end of the report pipe open now, so
+	// that reads return EOF once spark exits.
+	_ = rw.Close()
+	if err != nil {
 		return nil, errors.Wrap(err, errStartSpark)
 	}
 	if _, err := stdio.Stdin.Write(b); err != nil {
@@ -162,24 +339,70 @@ func (r *Runner) RunFunction(ctx context.Context, req *v1alpha1.RunFunctionReque
 	}
 
 	// We must read all of stdout and stderr before calling cmd.Wait, which
-	// closes the underlying pipes.
-	// Limited to MaxStdioBytes to avoid OOMing if the function writes a lot of
-	// data to stdout or stderr.
-	stdout, err := io.ReadAll(io.LimitReader(stdio.Stdout, MaxStdioBytes))
-	if err != nil {
-		return nil, errors.Wrap(err, errReadStdout)
+	// closes the underlying pipes. Limited to avoid OOMing if spark writes a
+	// lot of data to stdout or stderr.
+	stdout, stderr, serr := ReadStdio(stdio.Stdout, stdio.Stderr, MaxStdioBytes+sparkStdioOverheadBytes)
+	if serr != nil && !IsStdioLimitExceeded(serr) {
+		return nil, errors.Wrap(serr, errReadStdio)
+	}
+
+	// spark's stdout is its response, which is corrupt if it was truncated.
+	// Its stderr is only used to describe failures, so it can be truncated.
+	if serr != nil && !IsStdoutLimitExceeded(serr) {
+		r.log.Debug("Truncated spark's stderr", "image", req.GetImage(), "error", serr)
+		serr = nil
+	}
+
+	err = cmd.Wait()
+
+	// The report is best effort; we don't want to fail a function run because
+	// we couldn't record metrics about it. spark's descendants shouldn't hold
+	// the write end of the report pipe open, but we don't want to block
+	// forever if one does.
+	_ = rr.SetReadDeadline(time.Now().Add(reportReadTimeout))
+	if rpt, err := metrics.ReadReport(rr); err == nil {
+		r.metrics.ObserveReport(rpt)
 	}
 
-	stderr, err := io.ReadAll(io.LimitReader(stdio.Stderr, MaxStdioBytes))
 	if err != nil {
-		return nil, errors.Wrap(err, errReadStderr)
+		// spark writes a RunFunctionFailure to stdout when a run fails. If it
+		// didn't (e.g. because it was killed) we describe the failure as best
+		// we can using its stderr.
+		f := &v1alpha1.RunFunctionFailure{}
+		if uerr := proto.Unmarshal(stdout, f); uerr != nil || f.GetPhase() == v1alpha1.RunFunctionPhase_RUN_FUNCTION_PHASE_UNSPECIFIED {
+			f = &v1alpha1.RunFunctionFailure{Message: err.Error()}
+			SetStderrTail(f, stderr)
+			if ctx.Err() != nil {
+				f.Code = int32(status.FromContextError(ctx.Err()).Code())
+			}
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				f.TerminationReason = v1alpha1.TerminationReason_TERMINATION_REASON_TIMED_OUT
+			}
+		}
+		r.log.Debug("Function run failed", "image", req.GetImage(), "run-id", f.GetRunId(), "phase", f.GetPhase().String(), "error", f.GetMessage())
+		return nil, FailureStatus(f).Err()
 	}
 
-	if err := cmd.Wait(); err != nil {
-		// TODO(negz): Handle stderr being too long to be a useful error.
-		return nil, errors.Errorf("%w: %s", err, bytes.TrimSuffix(stderr, []byte("\n")))
+	// spark's output was truncated, so we can't unmarshal it.
+	if serr != nil {
+		f := &v1alpha1.RunFunctionFailure{Message: serr.Error(), Code: int32(codes.ResourceExhausted)}
+		SetStderrTail(f, stderr)
+		r.log.Debug("Function run failed", "image", req.GetImage(), "error", f.GetMessage())
+		return nil, FailureStatus(f).Err()
 	}
 
 	rsp := &v1alpha1.RunFunctionResponse{}
 	return rsp, errors.Wrap(proto.Unmarshal(stdout, rsp), errUnmarshalResponse)
 }
+
+// idRangeCacheDir returns the cache directory of the supplied ID range within
+// the supplied cache directory, creating it if necessary. The directory is
+// owned by the range's root UID and GID, so that spark can write to it. The
+// shared image and layer cache is mounted into it for each run.
+func idRangeCacheDir(cache string, ids IDRange) (string, error) {
+	dir := filepath.Join(cache, fmt.Sprintf("ids-%d", ids.Index))
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	return dir, os.Chown(dir, ids.UID, ids.GID)
+}
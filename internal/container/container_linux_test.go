@@ -0,0 +1,130 @@
+//go:build linux
+
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package container
+
+import (
+	"context"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/crossplane/function-runtime-oci/internal/proto/v1alpha1"
+)
+
+func TestListenAndServeInFlight(t *testing.T) {
+	type want struct {
+		code      codes.Code
+		cleanedUp bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		grace  time.Duration
+		// How long the function takes to run, unless it's cancelled.
+		runFor time.Duration
+		want   want
+	}{
+		"Drained": {
+			reason: "ListenAndServe should wait for an in-flight run to finish before it returns.",
+			grace:  10 * time.Second,
+			runFor: 500 * time.Millisecond,
+			want:   want{code: codes.OK, cleanedUp: true},
+		},
+		"Cancelled": {
+			reason: "ListenAndServe should cancel an in-flight run once its grace period expires, and wait for it to clean up before it returns.",
+			grace:  100 * time.Millisecond,
+			runFor: time.Minute,
+			// The server closes the connection, so the caller sees Unavailable.
+			want: want{code: codes.Unavailable, cleanedUp: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			started := make(chan struct{})
+			cleanedUp := &atomic.Bool{}
+
+			r := NewRunner(WithCacheDir(t.TempDir()), WithGracePeriod(tc.grace))
+			r.run = func(ctx context.Context, _ *v1alpha1.RunFunctionRequest) (*v1alpha1.RunFunctionResponse, error) {
+				close(started)
+				var err error
+				select {
+				case <-time.After(tc.runFor):
+				case <-ctx.Done():
+					err = status.FromContextError(ctx.Err()).Err()
+				}
+				// Cleaning up a cancelled run takes time, e.g. to kill and
+				// delete its container.
+				time.Sleep(100 * time.Millisecond)
+				cleanedUp.Store(true)
+				return &v1alpha1.RunFunctionResponse{}, err
+			}
+
+			sock := filepath.Join(t.TempDir(), "fn.sock")
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			done := make(chan error)
+			go func() {
+				done <- r.ListenAndServe(ctx, "unix", sock)
+			}()
+
+			dctx, dcancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer dcancel()
+			conn, err := grpc.DialContext(dctx, "unix://"+sock, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close() //nolint:errcheck // Only used in tests.
+
+			called := make(chan error)
+			go func() {
+				_, err := v1alpha1.NewContainerizedFunctionRunnerServiceClient(conn).RunFunction(context.Background(), &v1alpha1.RunFunctionRequest{Image: "example.org/fn"})
+				called <- err
+			}()
+
+			// Shut down while the function is running.
+			<-started
+			cancel()
+
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Errorf("\n%s\nListenAndServe(...): %v", tc.reason, err)
+				}
+			case <-time.After(10 * time.Second):
+				t.Fatalf("\n%s\nListenAndServe(...): did not return after its context was cancelled", tc.reason)
+			}
+
+			// Whether the run finished, or was cancelled, it should have
+			// cleaned up by the time ListenAndServe returned.
+			got := want{cleanedUp: cleanedUp.Load()}
+			got.code = status.Code(<-called)
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nListenAndServe(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -20,6 +20,7 @@ package container
 
 import (
 	"context"
+	"net"
 
 	"github.com/crossplane/crossplane-runtime/pkg/errors"
 
@@ -34,6 +35,17 @@ func HasCapSetUID() bool { return false }
 // HasCapSetGID returns false on non-Linux.
 func HasCapSetGID() bool { return false }
 
+// CanCreateUserNamespace returns an error on non-Linux.
+func CanCreateUserNamespace() error { return errors.New(errLinuxOnly) }
+
+// CanIDMapMount returns an error on non-Linux.
+func CanIDMapMount(_ string, _, _ IDRange) error { return errors.New(errLinuxOnly) }
+
+// GetPeerCredentials returns an error on non-Linux.
+func GetPeerCredentials(_ *net.UnixConn) (PeerCredentials, error) {
+	return PeerCredentials{}, errors.New(errLinuxOnly)
+}
+
 // RunFunction returns an error on non-Linux.
 func (r *Runner) RunFunction(_ context.Context, _ *v1alpha1.RunFunctionRequest) (*v1alpha1.RunFunctionResponse, error) {
 	return nil, errors.New(errLinuxOnly)
@@ -0,0 +1,49 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package container
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestListenAndServeShutdown(t *testing.T) {
+	r := NewRunner(WithCacheDir(t.TempDir()), WithGracePeriod(time.Second))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- r.ListenAndServe(ctx, "unix", filepath.Join(t.TempDir(), "fn.sock"))
+	}()
+
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("ListenAndServe(...): %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ListenAndServe(...): did not return after its context was cancelled")
+	}
+
+	if err := r.Ready(); err == nil {
+		t.Errorf("Ready(): want error while shutting down, got nil")
+	}
+}
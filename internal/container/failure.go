@@ -0,0 +1,72 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package container
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/crossplane/function-runtime-oci/internal/proto/v1alpha1"
+)
+
+// Error strings.
+const (
+	errFmtFailedPhase = "function run failed during %s phase: %s"
+)
+
+// MaxStderrTailBytes is the maximum number of bytes of a function's stderr
+// that will be included in a RunFunctionFailure. Only the tail of a longer
+// stderr is included.
+const MaxStderrTailBytes = 4 << 10 // 4 KB
+
+// SetStderrTail sets the supplied failure's stderr to the tail of the supplied
+// stderr, truncating it to MaxStderrTailBytes.
+func SetStderrTail(f *v1alpha1.RunFunctionFailure, stderr []byte) {
+	stderr = bytes.TrimSpace(stderr)
+	f.StderrTruncated = len(stderr) > MaxStderrTailBytes
+	if f.StderrTruncated {
+		stderr = stderr[len(stderr)-MaxStderrTailBytes:]
+	}
+	f.Stderr = stderr
+}
+
+// FailureStatus returns a gRPC status describing the supplied failure. The
+// status has the failure's code, or Unknown if it has none, and includes the
+// failure as a status detail. The status message names only the phase in which
+// the run failed and why; the function's stderr is only in the detail.
+func FailureStatus(f *v1alpha1.RunFunctionFailure) *status.Status {
+	msg := f.GetMessage()
+	if p := f.GetPhase(); p != v1alpha1.RunFunctionPhase_RUN_FUNCTION_PHASE_UNSPECIFIED {
+		phase := strings.ToLower(strings.TrimPrefix(p.String(), "RUN_FUNCTION_PHASE_"))
+		msg = fmt.Sprintf(errFmtFailedPhase, phase, msg)
+	}
+
+	c := codes.Code(f.GetCode())
+	if c == codes.OK {
+		c = codes.Unknown
+	}
+
+	s := status.New(c, msg)
+	if sd, err := s.WithDetails(f); err == nil {
+		return sd
+	}
+	return s
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package container
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"github.com/crossplane/function-runtime-oci/internal/proto/v1alpha1"
+)
+
+func TestSetStderrTail(t *testing.T) {
+	long := append(bytes.Repeat([]byte("a"), 10), bytes.Repeat([]byte("b"), MaxStderrTailBytes)...)
+
+	cases := map[string]struct {
+		reason string
+		stderr []byte
+		want   *v1alpha1.RunFunctionFailure
+	}{
+		"Short": {
+			reason: "Stderr that fits should be included in full, without trailing whitespace.",
+			stderr: []byte("boom\n"),
+			want:   &v1alpha1.RunFunctionFailure{Stderr: []byte("boom")},
+		},
+		"Long": {
+			reason: "Only the tail of stderr that doesn't fit should be included, and it should be marked truncated.",
+			stderr: long,
+			want:   &v1alpha1.RunFunctionFailure{Stderr: bytes.Repeat([]byte("b"), MaxStderrTailBytes), StderrTruncated: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := &v1alpha1.RunFunctionFailure{}
+			SetStderrTail(got, tc.stderr)
+			if diff := cmp.Diff(tc.want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("\n%s\nSetStderrTail(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestFailureStatus(t *testing.T) {
+	want := &v1alpha1.RunFunctionFailure{
+		RunId:    "cool-run",
+		Phase:    v1alpha1.RunFunctionPhase_RUN_FUNCTION_PHASE_RUN,
+		Message:  "OCI runtime error: exit status 1",
+		ExitCode: 1,
+		Stderr:   []byte("boom"),
+		Code:     int32(codes.ResourceExhausted),
+	}
+
+	s := FailureStatus(want)
+	if diff := cmp.Diff(codes.ResourceExhausted, s.Code()); diff != "" {
+		t.Errorf("FailureStatus(...): -want code, +got code:\n%s", diff)
+	}
+	// The function's stderr should only be in the detail.
+	if diff := cmp.Diff("function run failed during run phase: OCI runtime error: exit status 1", s.Message()); diff != "" {
+		t.Errorf("FailureStatus(...): -want message, +got message:\n%s", diff)
+	}
+
+	details := s.Details()
+	if len(details) != 1 {
+		t.Fatalf("FailureStatus(...): want 1 detail, got %d", len(details))
+	}
+	if diff := cmp.Diff(want, details[0], protocmp.Transform()); diff != "" {
+		t.Errorf("FailureStatus(...): -want detail, +got detail:\n%s", diff)
+	}
+}
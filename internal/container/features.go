@@ -0,0 +1,47 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"os/exec"
+
+	"github.com/opencontainers/runtime-spec/specs-go/features"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+)
+
+// Error strings.
+const (
+	errProbeFeatures  = "cannot run OCI runtime features subcommand"
+	errDecodeFeatures = "cannot decode OCI runtime features"
+)
+
+// ProbeRuntimeFeatures asks the supplied OCI runtime binary which features it
+// supports, per its features subcommand.
+func ProbeRuntimeFeatures(ctx context.Context, runtime string) (*features.Features, error) {
+	out, err := exec.CommandContext(ctx, runtime, "features").Output() //nolint:gosec // We're intentionally executing with variable input.
+	if err != nil {
+		return nil, errors.Wrap(err, errProbeFeatures)
+	}
+	f := &features.Features{}
+	if err := json.Unmarshal(out, f); err != nil {
+		return nil, errors.Wrap(err, errDecodeFeatures)
+	}
+	return f, nil
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package container
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/opencontainers/runtime-spec/specs-go/features"
+)
+
+func TestProbeRuntimeFeatures(t *testing.T) {
+	type want struct {
+		f   *features.Features
+		err bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		script string
+		want   want
+	}{
+		"Supported": {
+			reason: "The decoded output of the features subcommand should be returned.",
+			script: `echo '{"ociVersionMin":"1.0.0"}'`,
+			want:   want{f: &features.Features{OCIVersionMin: "1.0.0"}},
+		},
+		"Unsupported": {
+			reason: "We should return an error if the runtime has no features subcommand.",
+			script: "echo 'unknown command' >&2; exit 1",
+			want:   want{err: true},
+		},
+		"Invalid": {
+			reason: "We should return an error if the runtime's features can't be decoded.",
+			script: "echo wat",
+			want:   want{err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			rt := filepath.Join(t.TempDir(), "runtime")
+			if err := os.WriteFile(rt, []byte("#!/bin/sh\n"+tc.script+"\n"), 0o700); err != nil { //nolint:gosec // Executable by design.
+				t.Fatal(err)
+			}
+
+			f, err := ProbeRuntimeFeatures(context.Background(), rt)
+			got := want{f: f, err: err != nil}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nProbeRuntimeFeatures(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
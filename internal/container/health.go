@@ -0,0 +1,133 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package container
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"time"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+
+	"github.com/crossplane/function-runtime-oci/internal/proto/v1alpha1"
+)
+
+// Error strings.
+const (
+	errCacheDirNotWritable = "cache directory is not writable"
+	errRuntimeNotFound     = "cannot find OCI runtime binary"
+	errCreateUserNamespace = "cannot create user namespace"
+	errDraining            = "runner is shutting down"
+)
+
+// How often the gRPC health service re-evaluates readiness.
+const healthCheckInterval = 30 * time.Second
+
+// The result of checking whether the Runner can create user namespaces.
+type usernsCheck struct{ err error }
+
+// Ready returns an error if the Runner is not ready to run functions. It checks
+// that the Runner isn't shutting down, that the cache directory is writable,
+// that the OCI runtime binary exists, and that user namespaces can be created.
+// Whether user namespaces can be created is checked once, then only each time
+// the gRPC health service re-evaluates readiness.
+func (r *Runner) Ready() error {
+	if r.draining.Load() {
+		return errors.New(errDraining)
+	}
+
+	f, err := os.CreateTemp(r.cache, ".readyz-")
+	if err != nil {
+		return errors.Wrap(err, errCacheDirNotWritable)
+	}
+	_ = f.Close()
+	if err := os.Remove(f.Name()); err != nil {
+		return errors.Wrap(err, errCacheDirNotWritable)
+	}
+
+	if _, err := exec.LookPath(r.runtime); err != nil {
+		return errors.Wrap(err, errRuntimeNotFound)
+	}
+
+	c := r.usernsCheck.Load()
+	if c == nil {
+		c = r.checkUserNamespace()
+	}
+	return errors.Wrap(c.err, errCreateUserNamespace)
+}
+
+// checkUserNamespace checks whether the Runner can create user namespaces, and
+// records the result for Ready.
+func (r *Runner) checkUserNamespace() *usernsCheck {
+	c := &usernsCheck{err: r.userns()}
+	r.usernsCheck.Store(c)
+	return c
+}
+
+// HealthHandler returns an HTTP handler that serves /healthz and /readyz
+// endpoints. /healthz succeeds as long as the Runner is running. /readyz
+// succeeds only if the Runner is Ready.
+func (r *Runner) HealthHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, _ *http.Request) {
+		if err := r.Ready(); err != nil {
+			r.log.Debug("Runner is not ready", "error", err)
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	return mux
+}
+
+// watchHealth periodically updates the serving status of the supplied gRPC
+// health server per the Runner's readiness, until the supplied context is
+// done.
+func (r *Runner) watchHealth(ctx context.Context, hs *health.Server) {
+	set := func() {
+		r.checkUserNamespace()
+		s := healthpb.HealthCheckResponse_SERVING
+		if err := r.Ready(); err != nil {
+			r.log.Debug("Runner is not ready", "error", err)
+			s = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+		hs.SetServingStatus("", s)
+		hs.SetServingStatus(v1alpha1.ContainerizedFunctionRunnerService_ServiceDesc.ServiceName, s)
+	}
+
+	set()
+	t := time.NewTicker(healthCheckInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			hs.Shutdown()
+			return
+		case <-t.C:
+			set()
+		}
+	}
+}
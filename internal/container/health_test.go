@@ -0,0 +1,106 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package container
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+)
+
+func TestHealthHandler(t *testing.T) {
+	tmp := t.TempDir()
+
+	type want struct {
+		code int
+	}
+
+	cases := map[string]struct {
+		reason string
+		r      *Runner
+		path   string
+		want   want
+	}{
+		"Healthz": {
+			reason: "The health endpoint should succeed even if the runner isn't ready.",
+			r:      NewRunner(WithCacheDir(filepath.Join(tmp, "nonexistent"))),
+			path:   "/healthz",
+			want:   want{code: http.StatusOK},
+		},
+		"ReadyzCacheDirNotWritable": {
+			reason: "The readiness endpoint should fail if the cache directory isn't writable.",
+			r:      NewRunner(WithCacheDir(filepath.Join(tmp, "nonexistent"))),
+			path:   "/readyz",
+			want:   want{code: http.StatusServiceUnavailable},
+		},
+		"ReadyzRuntimeNotFound": {
+			reason: "The readiness endpoint should fail if the OCI runtime binary doesn't exist.",
+			r:      NewRunner(WithCacheDir(tmp), WithRuntime(filepath.Join(tmp, "nonexistent"))),
+			path:   "/readyz",
+			want:   want{code: http.StatusServiceUnavailable},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tc.r.HealthHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			if diff := cmp.Diff(tc.want.code, rec.Code); diff != "" {
+				t.Errorf("\n%s\nServeHTTP(...): -want status code, +got status code:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestReadyUserNamespace(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	calls := 0
+	r := NewRunner(WithCacheDir(t.TempDir()), WithRuntime("sh"))
+	r.userns = func() error {
+		calls++
+		return errBoom
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := r.Ready(); !errors.Is(err, errBoom) {
+			t.Errorf("Ready(): want error wrapping %v, got %v", errBoom, err)
+		}
+	}
+	if diff := cmp.Diff(1, calls); diff != "" {
+		t.Errorf("Ready(): -want user namespace checks, +got:\n%s", diff)
+	}
+
+	// Re-checking, as the gRPC health service does periodically, should update
+	// the result Ready uses.
+	r.userns = func() error {
+		calls++
+		return nil
+	}
+	r.checkUserNamespace()
+	if err := r.Ready(); err != nil {
+		t.Errorf("Ready(): want no error after a successful re-check, got %v", err)
+	}
+	if diff := cmp.Diff(2, calls); diff != "" {
+		t.Errorf("Ready(): -want user namespace checks, +got:\n%s", diff)
+	}
+}
@@ -0,0 +1,180 @@
+//go:build linux
+
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package container
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+
+	"github.com/crossplane/function-runtime-oci/internal/oci/store"
+)
+
+// Error strings.
+const (
+	errMkSharedCacheDir   = "cannot make shared cache directory"
+	errIDMapNamespace     = "cannot create user namespace to map IDs"
+	errOpenTree           = "cannot clone mount"
+	errSetIDMap           = "cannot map mount's IDs"
+	errMoveMount          = "cannot attach mount"
+	errMkIDMapProbeDir    = "cannot make directory to check for idmapped mount support"
+	errFmtMountCacheDir   = "cannot mount shared cache directory %q"
+	errFmtUnmountCacheDir = "cannot unmount shared cache directory %q"
+)
+
+// The cache directories that spark shares between ID ranges. The rest of the
+// cache directory - e.g. bundles, scratch space and secrets - is specific to a
+// function run, and is owned by its ID range.
+var sharedCacheDirs = []string{store.DirDigests, store.DirImages, store.DirOverlays, store.DirVerified}
+
+// mkSharedCacheDirs makes the directories that spark shares between ID ranges
+// within the supplied cache directory, owned by the supplied owner.
+func mkSharedCacheDirs(cache string, owner IDRange) error {
+	for _, d := range sharedCacheDirs {
+		path := filepath.Join(cache, d)
+		if err := os.MkdirAll(path, 0o700); err != nil {
+			return errors.Wrap(err, errMkSharedCacheDir)
+		}
+		if err := os.Chown(path, owner.UID, owner.GID); err != nil {
+			return errors.Wrap(err, errMkSharedCacheDir)
+		}
+	}
+	return nil
+}
+
+// mountSharedCache mounts the directories of the supplied shared cache that
+// spark shares between ID ranges into the supplied cache directory of an ID
+// range. The shared directories are owned by the supplied owner. The mounts
+// map the owner's IDs to the range's, so that spark can read and write them as
+// if the range owned them, while what it writes is still owned by the owner.
+// The returned function unmounts them.
+func mountSharedCache(shared, dir string, owner, ids IDRange) (func() error, error) {
+	ns, err := idMapNamespace(owner, ids)
+	if err != nil {
+		return nil, errors.Wrap(err, errIDMapNamespace)
+	}
+	defer ns.Close() //nolint:errcheck // The mounts hold a reference to the namespace.
+
+	mounted := make([]string, 0, len(sharedCacheDirs))
+	unmount := func() error {
+		for _, path := range mounted {
+			if err := unix.Unmount(path, unix.MNT_DETACH); err != nil {
+				return errors.Wrapf(err, errFmtUnmountCacheDir, path)
+			}
+		}
+		return nil
+	}
+
+	for _, d := range sharedCacheDirs {
+		path := filepath.Join(dir, d)
+		if err := os.MkdirAll(path, 0o700); err != nil {
+			_ = unmount()
+			return nil, errors.Wrapf(err, errFmtMountCacheDir, path)
+		}
+		if err := idMapMount(ns, filepath.Join(shared, d), path); err != nil {
+			_ = unmount()
+			return nil, errors.Wrapf(err, errFmtMountCacheDir, path)
+		}
+		mounted = append(mounted, path)
+	}
+	return unmount, nil
+}
+
+// CanIDMapMount returns an error if this process can't make idmapped mounts
+// within the supplied directory, which is required to share a cache directory
+// between ID ranges. Idmapped mounts require CAP_SYS_ADMIN, and a kernel and
+// filesystem that support them.
+func CanIDMapMount(dir string, owner, ids IDRange) error {
+	tmp, err := os.MkdirTemp(dir, ".idmap-")
+	if err != nil {
+		return errors.Wrap(err, errMkIDMapProbeDir)
+	}
+	defer os.RemoveAll(tmp) //nolint:errcheck // Nothing to do if this fails.
+
+	src, dst := filepath.Join(tmp, "src"), filepath.Join(tmp, "dst")
+	for _, path := range []string{src, dst} {
+		if err := os.Mkdir(path, 0o700); err != nil {
+			return errors.Wrap(err, errMkIDMapProbeDir)
+		}
+	}
+
+	ns, err := idMapNamespace(owner, ids)
+	if err != nil {
+		return errors.Wrap(err, errIDMapNamespace)
+	}
+	defer ns.Close() //nolint:errcheck // Nothing to do if this fails.
+
+	if err := idMapMount(ns, src, dst); err != nil {
+		return err
+	}
+	return errors.Wrapf(unix.Unmount(dst, unix.MNT_DETACH), errFmtUnmountCacheDir, dst)
+}
+
+// idMapNamespace returns a user namespace that maps the supplied owner's IDs
+// to the supplied range's IDs.
+//
+// A user namespace only exists as long as a process or file descriptor refers
+// to it, so we start a process in a new one, open the new namespace, then kill
+// the process. The process is traced, so it stops before it runs anything.
+func idMapNamespace(owner, ids IDRange) (*os.File, error) {
+	// The thread that starts a traced process must be the one that waits for
+	// it.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	cmd := exec.Command(os.Args[0]) //nolint:gosec // We're intentionally executing with variable input.
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags:  syscall.CLONE_NEWUSER,
+		UidMappings: []syscall.SysProcIDMap{{ContainerID: owner.UID, HostID: ids.UID, Size: UserNamespaceUIDs}},
+		GidMappings: []syscall.SysProcIDMap{{ContainerID: owner.GID, HostID: ids.GID, Size: UserNamespaceGIDs}},
+		Ptrace:      true,
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+	return os.Open(fmt.Sprintf("/proc/%d/ns/user", cmd.Process.Pid))
+}
+
+// idMapMount mounts the supplied source directory at the supplied target,
+// mapping its IDs per the supplied user namespace. A file owned by an ID inside
+// the namespace appears to be owned by the corresponding ID outside it.
+func idMapMount(ns *os.File, source, target string) error {
+	fd, err := unix.OpenTree(unix.AT_FDCWD, source, unix.OPEN_TREE_CLONE|unix.OPEN_TREE_CLOEXEC)
+	if err != nil {
+		return errors.Wrap(err, errOpenTree)
+	}
+	defer unix.Close(fd) //nolint:errcheck // Nothing to do if this fails.
+
+	attr := &unix.MountAttr{Attr_set: unix.MOUNT_ATTR_IDMAP, Userns_fd: uint64(ns.Fd())}
+	if err := unix.MountSetattr(fd, "", unix.AT_EMPTY_PATH, attr); err != nil {
+		return errors.Wrap(err, errSetIDMap)
+	}
+	return errors.Wrap(unix.MoveMount(fd, "", unix.AT_FDCWD, target, unix.MOVE_MOUNT_F_EMPTY_PATH), errMoveMount)
+}
@@ -0,0 +1,72 @@
+//go:build linux
+
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package container
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/sys/unix"
+
+	"github.com/crossplane/function-runtime-oci/internal/oci/store"
+)
+
+func TestMountSharedCache(t *testing.T) {
+	cache := t.TempDir()
+	owner := IDRange{UID: 100000, GID: 100000}
+	ids := IDRange{UID: 200000, GID: 300000}
+	if err := CanIDMapMount(cache, owner, ids); err != nil {
+		t.Skipf("cannot make idmapped mounts: %v", err)
+	}
+
+	if err := mkSharedCacheDirs(cache, owner); err != nil {
+		t.Fatal(err)
+	}
+	cached := filepath.Join(cache, store.DirImages, "cool-layer")
+	if err := os.WriteFile(cached, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chown(cached, owner.UID+5, owner.GID+5); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join(cache, "ids-0")
+	unmount, err := mountSharedCache(cache, dir, owner, ids)
+	if err != nil {
+		t.Fatalf("mountSharedCache(...): %v", err)
+	}
+
+	// The shared cache should appear to be owned by the range.
+	st := &unix.Stat_t{}
+	if err := unix.Stat(filepath.Join(dir, store.DirImages, "cool-layer"), st); err != nil {
+		t.Fatalf("mountSharedCache(...): cannot stat cached file via range's cache directory: %v", err)
+	}
+	if diff := cmp.Diff([]uint32{uint32(ids.UID + 5), uint32(ids.GID + 5)}, []uint32{st.Uid, st.Gid}); diff != "" {
+		t.Errorf("mountSharedCache(...): -want cached file owner, +got:\n%s", diff)
+	}
+
+	if err := unmount(); err != nil {
+		t.Fatalf("mountSharedCache(...): unmount: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, store.DirImages, "cool-layer")); !os.IsNotExist(err) {
+		t.Errorf("mountSharedCache(...): want shared cache to be unmounted, got stat error %v", err)
+	}
+}
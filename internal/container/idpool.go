@@ -0,0 +1,175 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package container
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+)
+
+// Error strings.
+const (
+	errNoFreeIDRange = "no free user namespace ID ranges"
+	errOpenSubIDFile = "cannot open subordinate ID file"
+	errReadSubIDFile = "cannot read subordinate ID file"
+
+	errFmtParseSubIDLine = "cannot parse subordinate ID file line %q"
+	errFmtNoSubIDs       = "no subordinate IDs for user %q"
+	errFmtSubIDsTooSmall = "subordinate IDs for user %q can't fit a user namespace of %d IDs"
+)
+
+// A SubIDRange is a contiguous range of subordinate UIDs or GIDs, as found in
+// /etc/subuid or /etc/subgid.
+type SubIDRange struct {
+	Start int
+	Count int
+}
+
+// ReadSubIDRange returns the first range of subordinate IDs that the supplied
+// subordinate ID file (e.g. /etc/subuid) allocates to the supplied user. The
+// user may be a name or a numeric ID.
+func ReadSubIDRange(path, user string) (SubIDRange, error) {
+	f, err := os.Open(path) //nolint:gosec // Reading a variable path is intentional.
+	if err != nil {
+		return SubIDRange{}, errors.Wrap(err, errOpenSubIDFile)
+	}
+	defer f.Close() //nolint:errcheck // Only open for reading.
+	return ParseSubIDRange(f, user)
+}
+
+// ParseSubIDRange returns the first range of subordinate IDs that the supplied
+// subordinate ID file data allocates to the supplied user. Each line of the
+// data is of the form user:start:count.
+func ParseSubIDRange(r io.Reader, user string) (SubIDRange, error) {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.Split(line, ":")
+		if len(parts) != 3 {
+			return SubIDRange{}, errors.Errorf(errFmtParseSubIDLine, line)
+		}
+		if parts[0] != user {
+			continue
+		}
+		start, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return SubIDRange{}, errors.Wrapf(err, errFmtParseSubIDLine, line)
+		}
+		count, err := strconv.Atoi(parts[2])
+		if err != nil {
+			return SubIDRange{}, errors.Wrapf(err, errFmtParseSubIDLine, line)
+		}
+		return SubIDRange{Start: start, Count: count}, nil
+	}
+	if err := s.Err(); err != nil {
+		return SubIDRange{}, errors.Wrap(err, errReadSubIDFile)
+	}
+	return SubIDRange{}, errors.Errorf(errFmtNoSubIDs, user)
+}
+
+// An IDRange is a range of UserNamespaceUIDs host UIDs and UserNamespaceGIDs
+// host GIDs that are mapped to a function run's user namespace.
+type IDRange struct {
+	// Index of the range within its pool.
+	Index int
+
+	// UID and GID map to root in the user namespace.
+	UID int
+	GID int
+}
+
+// An IDPool allocates each concurrent function run a distinct range of host
+// UIDs and GIDs, so that one run's processes can't access files left by
+// another's.
+type IDPool struct {
+	uids  SubIDRange
+	gids  SubIDRange
+	mu    sync.Mutex
+	inUse []bool
+}
+
+// NewIDPool returns a pool that allocates ranges from the supplied UIDs and
+// GIDs. It returns an error if they can't fit at least one range.
+func NewIDPool(user string, uids, gids SubIDRange) (*IDPool, error) {
+	n := uids.Count / UserNamespaceUIDs
+	if g := gids.Count / UserNamespaceGIDs; g < n {
+		n = g
+	}
+	if n < 1 {
+		return nil, errors.Errorf(errFmtSubIDsTooSmall, user, UserNamespaceUIDs)
+	}
+	return &IDPool{uids: uids, gids: gids, inUse: make([]bool, n)}, nil
+}
+
+// Size returns the number of ranges in the pool; i.e. the maximum number of
+// concurrent function runs it supports.
+func (p *IDPool) Size() int {
+	return len(p.inUse)
+}
+
+// Overlaps returns true if the supplied range of UserNamespaceUIDs UIDs and
+// UserNamespaceGIDs GIDs overlaps any range in the pool.
+func (p *IDPool) Overlaps(r IDRange) bool {
+	overlaps := func(start, size, pstart, psize int) bool {
+		return start < pstart+psize && pstart < start+size
+	}
+	return overlaps(r.UID, UserNamespaceUIDs, p.uids.Start, p.Size()*UserNamespaceUIDs) ||
+		overlaps(r.GID, UserNamespaceGIDs, p.gids.Start, p.Size()*UserNamespaceGIDs)
+}
+
+// Acquire the lowest free range of IDs. The returned function must be called
+// to release the range once the run is done. Acquire returns a gRPC
+// ResourceExhausted status error if all ranges are in use.
+func (p *IDPool) Acquire() (IDRange, func(), error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// We always prefer the lowest free range. Each range has its own cache
+	// directory for what's specific to a run, so this keeps the directories
+	// that are in use to a minimum.
+	for i := range p.inUse {
+		if p.inUse[i] {
+			continue
+		}
+		p.inUse[i] = true
+		r := IDRange{
+			Index: i,
+			UID:   p.uids.Start + i*UserNamespaceUIDs,
+			GID:   p.gids.Start + i*UserNamespaceGIDs,
+		}
+		return r, func() { p.release(i) }, nil
+	}
+	return IDRange{}, nil, status.Error(codes.ResourceExhausted, errNoFreeIDRange)
+}
+
+func (p *IDPool) release(i int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.inUse[i] = false
+}
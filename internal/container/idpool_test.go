@@ -0,0 +1,203 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package container
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+func TestParseSubIDRange(t *testing.T) {
+	type args struct {
+		data string
+		user string
+	}
+	type want struct {
+		r   SubIDRange
+		err error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"Found": {
+			reason: "We should return the first range allocated to the user.",
+			args: args{
+				data: "# Comment\nother:100000:65536\ncool:165536:131072\ncool:500000:65536\n",
+				user: "cool",
+			},
+			want: want{r: SubIDRange{Start: 165536, Count: 131072}},
+		},
+		"NotFound": {
+			reason: "We should return an error if no range is allocated to the user.",
+			args: args{
+				data: "other:100000:65536\n",
+				user: "cool",
+			},
+			want: want{err: errors.Errorf(errFmtNoSubIDs, "cool")},
+		},
+		"Malformed": {
+			reason: "We should return an error if a line can't be parsed.",
+			args: args{
+				data: "cool:100000\n",
+				user: "cool",
+			},
+			want: want{err: errors.Errorf(errFmtParseSubIDLine, "cool:100000")},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			r, err := ParseSubIDRange(strings.NewReader(tc.args.data), tc.args.user)
+			if diff := cmp.Diff(tc.want.r, r); diff != "" {
+				t.Errorf("\n%s\nParseSubIDRange(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nParseSubIDRange(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestNewIDPool(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		uids   SubIDRange
+		gids   SubIDRange
+		size   int
+		err    error
+	}{
+		"FitsRanges": {
+			reason: "The pool should hold as many ranges as both the UIDs and GIDs can fit.",
+			uids:   SubIDRange{Start: 100000, Count: 4 * UserNamespaceUIDs},
+			gids:   SubIDRange{Start: 100000, Count: 3*UserNamespaceGIDs + 10},
+			size:   3,
+		},
+		"TooSmall": {
+			reason: "We should return an error if the IDs can't fit a single range.",
+			uids:   SubIDRange{Start: 100000, Count: UserNamespaceUIDs - 1},
+			gids:   SubIDRange{Start: 100000, Count: UserNamespaceGIDs},
+			err:    errors.Errorf(errFmtSubIDsTooSmall, "cool", UserNamespaceUIDs),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p, err := NewIDPool("cool", tc.uids, tc.gids)
+			if diff := cmp.Diff(tc.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nNewIDPool(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tc.size, p.Size()); diff != "" {
+				t.Errorf("\n%s\nSize(): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestIDPoolAcquire(t *testing.T) {
+	p, err := NewIDPool("cool", SubIDRange{Start: 100000, Count: 2 * UserNamespaceUIDs}, SubIDRange{Start: 200000, Count: 2 * UserNamespaceGIDs})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, releaseFirst, err := p.Acquire()
+	if err != nil {
+		t.Fatalf("Acquire(): %v", err)
+	}
+	if diff := cmp.Diff(IDRange{Index: 0, UID: 100000, GID: 200000}, first); diff != "" {
+		t.Errorf("Acquire(): -want first range, +got:\n%s", diff)
+	}
+
+	second, releaseSecond, err := p.Acquire()
+	if err != nil {
+		t.Fatalf("Acquire(): %v", err)
+	}
+	want := IDRange{Index: 1, UID: 100000 + UserNamespaceUIDs, GID: 200000 + UserNamespaceGIDs}
+	if diff := cmp.Diff(want, second); diff != "" {
+		t.Errorf("Acquire(): -want second range, +got:\n%s", diff)
+	}
+
+	if _, _, err := p.Acquire(); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Acquire(): want ResourceExhausted when all ranges are in use, got %v", err)
+	}
+
+	// We should reuse the lowest free range once it's released.
+	releaseFirst()
+	again, releaseAgain, err := p.Acquire()
+	if err != nil {
+		t.Fatalf("Acquire(): %v", err)
+	}
+	if diff := cmp.Diff(first, again); diff != "" {
+		t.Errorf("Acquire(): -want released range, +got:\n%s", diff)
+	}
+	releaseAgain()
+	releaseSecond()
+}
+
+func TestIDPoolOverlaps(t *testing.T) {
+	p, err := NewIDPool("cool", SubIDRange{Start: 200000, Count: 2 * UserNamespaceUIDs}, SubIDRange{Start: 300000, Count: 2 * UserNamespaceGIDs})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]struct {
+		reason string
+		r      IDRange
+		want   bool
+	}{
+		"Below": {
+			reason: "A range that ends before the pool starts shouldn't overlap it.",
+			r:      IDRange{UID: 200000 - UserNamespaceUIDs, GID: 300000 - UserNamespaceGIDs},
+			want:   false,
+		},
+		"Above": {
+			reason: "A range that starts after the pool ends shouldn't overlap it.",
+			r:      IDRange{UID: 200000 + 2*UserNamespaceUIDs, GID: 300000 + 2*UserNamespaceGIDs},
+			want:   false,
+		},
+		"UIDsOverlap": {
+			reason: "A range whose UIDs overlap the pool's should overlap it.",
+			r:      IDRange{UID: 200000 - 1, GID: 0},
+			want:   true,
+		},
+		"GIDsOverlap": {
+			reason: "A range whose GIDs overlap the pool's should overlap it.",
+			r:      IDRange{UID: 0, GID: 300000 + 2*UserNamespaceGIDs - 1},
+			want:   true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, p.Overlaps(tc.r)); diff != "" {
+				t.Errorf("\n%s\nOverlaps(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
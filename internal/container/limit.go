@@ -0,0 +1,162 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package container
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Error strings.
+const (
+	errFmtQueueFull    = "too many function runs in flight (max %d) and queued (max %d)"
+	errFmtQueueTimeout = "timed out after %s waiting for one of %d function run slots"
+)
+
+// A Limiter bounds how many function runs may be in flight at once. Runs that
+// arrive when all slots are in use wait in a bounded queue until a slot frees
+// up, they time out, or their context is cancelled. Queued runs are granted
+// slots in the order they arrived.
+type Limiter struct {
+	maxRuns   int
+	maxQueued int
+	timeout   time.Duration
+
+	mu       sync.Mutex
+	inFlight int
+
+	// Each queued run waits for its channel to be closed, which hands it the
+	// slot released by a finished run.
+	waiting *list.List
+}
+
+// NewLimiter returns a Limiter that allows up to maxRuns concurrent function
+// runs, with up to maxQueued runs waiting for up to timeout for a slot. A
+// maxRuns of zero or less disables limiting. A timeout of zero or less means
+// queued runs wait until their context is done.
+func NewLimiter(maxRuns, maxQueued int, timeout time.Duration) *Limiter {
+	if maxRuns <= 0 {
+		return &Limiter{}
+	}
+	if maxQueued < 0 {
+		maxQueued = 0
+	}
+	return &Limiter{
+		maxRuns:   maxRuns,
+		maxQueued: maxQueued,
+		timeout:   timeout,
+		waiting:   list.New(),
+	}
+}
+
+// Acquire a function run slot. The returned function must be called to release
+// the slot once the run is done. Acquire returns a gRPC ResourceExhausted
+// status error if the queue is full, or if it times out waiting in the queue.
+func (l *Limiter) Acquire(ctx context.Context) (func(), error) {
+	if l == nil || l.maxRuns <= 0 {
+		return func() {}, nil
+	}
+
+	l.mu.Lock()
+
+	// Take a slot straight away if one is free and no-one is queued for it.
+	if l.inFlight < l.maxRuns && l.waiting.Len() == 0 {
+		l.inFlight++
+		l.mu.Unlock()
+		return l.release, nil
+	}
+
+	// Otherwise join the back of the queue, if there's room.
+	if l.waiting.Len() >= l.maxQueued {
+		l.mu.Unlock()
+		return nil, status.Errorf(codes.ResourceExhausted, errFmtQueueFull, l.maxRuns, l.maxQueued)
+	}
+	ready := make(chan struct{})
+	e := l.waiting.PushBack(ready)
+	l.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if l.timeout > 0 {
+		t := time.NewTimer(l.timeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+
+	var err error
+	select {
+	case <-ready:
+		return l.release, nil
+	case <-timeout:
+		err = status.Errorf(codes.ResourceExhausted, errFmtQueueTimeout, l.timeout, l.maxRuns)
+	case <-ctx.Done():
+		err = status.FromContextError(ctx.Err()).Err()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	select {
+	case <-ready:
+		// We were handed a slot as we gave up. Pass it on.
+		l.releaseLocked()
+	default:
+		l.waiting.Remove(e)
+	}
+	return nil, err
+}
+
+// InFlight returns the number of function runs currently holding a slot.
+func (l *Limiter) InFlight() int {
+	if l == nil || l.maxRuns <= 0 {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inFlight
+}
+
+// Queued returns the number of function runs currently waiting for a slot.
+func (l *Limiter) Queued() int {
+	if l == nil || l.maxRuns <= 0 {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.waiting.Len()
+}
+
+func (l *Limiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.releaseLocked()
+}
+
+// releaseLocked hands a slot to the run that has been queued longest, if any.
+// The caller must hold l.mu.
+func (l *Limiter) releaseLocked() {
+	e := l.waiting.Front()
+	if e == nil {
+		l.inFlight--
+		return
+	}
+	l.waiting.Remove(e)
+	close(e.Value.(chan struct{})) //nolint:forcetypeassert // We only push chan struct{}.
+}
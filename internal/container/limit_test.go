@@ -0,0 +1,200 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package container
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestLimiterAcquire(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	type args struct {
+		ctx context.Context
+	}
+	type want struct {
+		code     codes.Code
+		inFlight int
+	}
+
+	cases := map[string]struct {
+		reason string
+		l      *Limiter
+		held   int
+		args   args
+		want   want
+	}{
+		"Unlimited": {
+			reason: "A limiter with no maximum should always grant a slot.",
+			l:      NewLimiter(0, 0, 0),
+			held:   10,
+			args:   args{ctx: context.Background()},
+			want:   want{code: codes.OK},
+		},
+		"SlotAvailable": {
+			reason: "We should immediately grant a slot if one is free.",
+			l:      NewLimiter(2, 0, 0),
+			held:   1,
+			args:   args{ctx: context.Background()},
+			want:   want{code: codes.OK, inFlight: 2},
+		},
+		"QueueFull": {
+			reason: "We should return ResourceExhausted if no slot is free and there's no room in the queue.",
+			l:      NewLimiter(1, 0, 0),
+			held:   1,
+			args:   args{ctx: context.Background()},
+			want:   want{code: codes.ResourceExhausted, inFlight: 1},
+		},
+		"QueueTimeout": {
+			reason: "We should return ResourceExhausted if we time out waiting in the queue.",
+			l:      NewLimiter(1, 1, 10*time.Millisecond),
+			held:   1,
+			args:   args{ctx: context.Background()},
+			want:   want{code: codes.ResourceExhausted, inFlight: 1},
+		},
+		"ContextCancelled": {
+			reason: "We should return Canceled if the caller gives up while queued.",
+			l:      NewLimiter(1, 1, 0),
+			held:   1,
+			args:   args{ctx: cancelled},
+			want:   want{code: codes.Canceled, inFlight: 1},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < tc.held; i++ {
+				if _, err := tc.l.Acquire(context.Background()); err != nil {
+					t.Fatalf("Acquire(...): unexpected error acquiring held slot: %v", err)
+				}
+			}
+
+			release, err := tc.l.Acquire(tc.args.ctx)
+			if diff := cmp.Diff(tc.want.code, status.Code(err)); diff != "" {
+				t.Errorf("\n%s\nAcquire(...): -want code, +got code:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.inFlight, tc.l.InFlight()); diff != "" {
+				t.Errorf("\n%s\nInFlight(): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(0, tc.l.Queued()); diff != "" {
+				t.Errorf("\n%s\nQueued(): -want, +got:\n%s", tc.reason, diff)
+			}
+			if err == nil {
+				release()
+			}
+		})
+	}
+}
+
+func TestLimiterQueue(t *testing.T) {
+	l := NewLimiter(1, 1, 0)
+
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire(...): unexpected error: %v", err)
+	}
+
+	// This run should wait in the queue until the first run releases its slot.
+	done := make(chan error)
+	go func() {
+		r, err := l.Acquire(context.Background())
+		if err == nil {
+			r()
+		}
+		done <- err
+	}()
+
+	// Wait for the second run to be queued.
+	for l.Queued() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The queue is now full, so this run should be rejected.
+	if _, err := l.Acquire(context.Background()); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Acquire(...): want ResourceExhausted, got %v", err)
+	}
+
+	release()
+	if err := <-done; err != nil {
+		t.Errorf("Acquire(...): queued run: unexpected error: %v", err)
+	}
+	if l.InFlight() != 0 || l.Queued() != 0 {
+		t.Errorf("Limiter: want no runs in flight or queued, got %d in flight and %d queued", l.InFlight(), l.Queued())
+	}
+}
+
+func TestLimiterFairness(t *testing.T) {
+	l := NewLimiter(1, 2, 0)
+
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire(...): unexpected error: %v", err)
+	}
+
+	// Queue two runs, one after the other. Each reports its name once it gets
+	// a slot, and holds the slot until told to release it.
+	got := make(chan string)
+	done := make(chan struct{})
+	for i, name := range []string{"first", "second"} {
+		name := name
+		go func() {
+			r, err := l.Acquire(context.Background())
+			if err != nil {
+				t.Errorf("Acquire(...): %s queued run: unexpected error: %v", name, err)
+				return
+			}
+			got <- name
+			<-done
+			r()
+		}()
+		for l.Queued() != i+1 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// The released slot belongs to the first queued run, so a new run mustn't
+	// take it. It should queue instead, and give up when its context does.
+	release()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(ctx); status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("Acquire(...): new run: want DeadlineExceeded while runs are queued, got %v", err)
+	}
+
+	if diff := cmp.Diff("first", <-got); diff != "" {
+		t.Errorf("Acquire(...): -want run granted a slot, +got:\n%s", diff)
+	}
+	done <- struct{}{}
+	if diff := cmp.Diff("second", <-got); diff != "" {
+		t.Errorf("Acquire(...): -want run granted a slot, +got:\n%s", diff)
+	}
+	done <- struct{}{}
+
+	for l.InFlight() != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if l.Queued() != 0 {
+		t.Errorf("Limiter: want no runs queued, got %d", l.Queued())
+	}
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package container
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/crossplane/function-runtime-oci/internal/oci/spec"
+	"github.com/crossplane/function-runtime-oci/internal/proto/v1alpha1"
+)
+
+// Error strings.
+const (
+	errFmtExceedsCeiling = "requested %s limit %d exceeds the maximum of %d"
+)
+
+// A Policy constrains how functions are run, regardless of what their
+// RunFunctionRequests ask for.
+type Policy struct {
+	// DropAllCapabilities drops all capabilities from every function's
+	// container.
+	DropAllCapabilities bool
+
+	// MaxPids is the maximum pids limit a function may request. Zero means
+	// there is no maximum.
+	MaxPids int64
+
+	// MaxOpenFiles is the maximum RLIMIT_NOFILE a function may request. Zero
+	// means there is no maximum.
+	MaxOpenFiles uint64
+
+	// MaxProcesses is the maximum RLIMIT_NPROC a function may request. Zero
+	// means there is no maximum.
+	MaxProcesses uint64
+
+	// MaxCoreFileSize is the maximum RLIMIT_CORE a function may request. Zero
+	// means there is no maximum.
+	MaxCoreFileSize uint64
+}
+
+// Apply the policy to the supplied RunFunctionConfig. It returns a copy of
+// the config, or an InvalidArgument status error if the config asks for more
+// than the policy allows. A limit the config doesn't set is set to the
+// policy's maximum, unless the default limit is lower.
+func (p Policy) Apply(cfg *v1alpha1.RunFunctionConfig) (*v1alpha1.RunFunctionConfig, error) {
+	out := &v1alpha1.RunFunctionConfig{}
+	if cfg != nil {
+		out = proto.Clone(cfg).(*v1alpha1.RunFunctionConfig)
+	}
+	if out.Resources == nil {
+		out.Resources = &v1alpha1.ResourceConfig{}
+	}
+	if out.Resources.Limits == nil {
+		out.Resources.Limits = &v1alpha1.ResourceLimits{}
+	}
+	l := out.Resources.Limits
+
+	if p.MaxPids > 0 {
+		if l.Pids == nil {
+			l.Pids = proto.Int64(p.MaxPids)
+			if spec.DefaultPidsLimit < p.MaxPids {
+				l.Pids = proto.Int64(spec.DefaultPidsLimit)
+			}
+		}
+		// A limit of zero or less means no limit.
+		if l.GetPids() <= 0 || l.GetPids() > p.MaxPids {
+			return nil, status.Errorf(codes.InvalidArgument, errFmtExceedsCeiling, "pids", l.GetPids(), p.MaxPids)
+		}
+	}
+
+	rlimits := []struct {
+		name  string
+		limit **uint64
+		max   uint64
+		def   uint64
+	}{
+		{name: "open files", limit: &l.OpenFiles, max: p.MaxOpenFiles, def: spec.DefaultOpenFilesLimit},
+		{name: "processes", limit: &l.Processes, max: p.MaxProcesses},
+		{name: "core file size", limit: &l.CoreFileSize, max: p.MaxCoreFileSize},
+	}
+	for _, rl := range rlimits {
+		if rl.max == 0 {
+			continue
+		}
+		if *rl.limit == nil {
+			*rl.limit = proto.Uint64(rl.max)
+			if rl.def > 0 && rl.def < rl.max {
+				*rl.limit = proto.Uint64(rl.def)
+			}
+		}
+		if **rl.limit > rl.max {
+			return nil, status.Errorf(codes.InvalidArgument, errFmtExceedsCeiling, rl.name, **rl.limit, rl.max)
+		}
+	}
+
+	if p.DropAllCapabilities {
+		if out.Security == nil {
+			out.Security = &v1alpha1.SecurityConfig{}
+		}
+		out.Security.DropAllCapabilities = true
+	}
+
+	return out, nil
+}
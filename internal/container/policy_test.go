@@ -0,0 +1,127 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package container
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"github.com/crossplane/function-runtime-oci/internal/proto/v1alpha1"
+)
+
+func TestPolicyApply(t *testing.T) {
+	type want struct {
+		cfg  *v1alpha1.RunFunctionConfig
+		code codes.Code
+	}
+
+	cases := map[string]struct {
+		reason string
+		p      Policy
+		cfg    *v1alpha1.RunFunctionConfig
+		want   want
+	}{
+		"NoPolicy": {
+			reason: "An empty policy shouldn't change the config.",
+			cfg: &v1alpha1.RunFunctionConfig{
+				Resources: &v1alpha1.ResourceConfig{Limits: &v1alpha1.ResourceLimits{Pids: proto.Int64(-1)}},
+			},
+			want: want{
+				cfg: &v1alpha1.RunFunctionConfig{
+					Resources: &v1alpha1.ResourceConfig{Limits: &v1alpha1.ResourceLimits{Pids: proto.Int64(-1)}},
+				},
+			},
+		},
+		"WithinLimits": {
+			reason: "A config that asks for no more than the policy allows should be unchanged.",
+			p:      Policy{MaxPids: 100, MaxOpenFiles: 100},
+			cfg: &v1alpha1.RunFunctionConfig{
+				Resources: &v1alpha1.ResourceConfig{Limits: &v1alpha1.ResourceLimits{Pids: proto.Int64(100), OpenFiles: proto.Uint64(50)}},
+			},
+			want: want{
+				cfg: &v1alpha1.RunFunctionConfig{
+					Resources: &v1alpha1.ResourceConfig{Limits: &v1alpha1.ResourceLimits{Pids: proto.Int64(100), OpenFiles: proto.Uint64(50)}},
+				},
+			},
+		},
+		"DefaultLimits": {
+			reason: "Unset limits should be set to the policy's maximum, unless the default is lower.",
+			p:      Policy{MaxPids: 100, MaxOpenFiles: 1 << 20, MaxProcesses: 10, DropAllCapabilities: true},
+			want: want{
+				cfg: &v1alpha1.RunFunctionConfig{
+					Resources: &v1alpha1.ResourceConfig{Limits: &v1alpha1.ResourceLimits{
+						Pids:      proto.Int64(100),
+						OpenFiles: proto.Uint64(1024),
+						Processes: proto.Uint64(10),
+					}},
+					Security: &v1alpha1.SecurityConfig{DropAllCapabilities: true},
+				},
+			},
+		},
+		"UnlimitedPids": {
+			reason: "A config that asks for unlimited pids should be rejected if there's a maximum.",
+			p:      Policy{MaxPids: 100},
+			cfg: &v1alpha1.RunFunctionConfig{
+				Resources: &v1alpha1.ResourceConfig{Limits: &v1alpha1.ResourceLimits{Pids: proto.Int64(-1)}},
+			},
+			want: want{code: codes.InvalidArgument},
+		},
+		"TooManyOpenFiles": {
+			reason: "A config that asks for more open files than the policy allows should be rejected.",
+			p:      Policy{MaxOpenFiles: 100},
+			cfg: &v1alpha1.RunFunctionConfig{
+				Resources: &v1alpha1.ResourceConfig{Limits: &v1alpha1.ResourceLimits{OpenFiles: proto.Uint64(101)}},
+			},
+			want: want{code: codes.InvalidArgument},
+		},
+		"CoreFileTooLarge": {
+			reason: "A config that asks for a larger core file than the policy allows should be rejected.",
+			p:      Policy{MaxCoreFileSize: 100},
+			cfg: &v1alpha1.RunFunctionConfig{
+				Resources: &v1alpha1.ResourceConfig{Limits: &v1alpha1.ResourceLimits{CoreFileSize: proto.Uint64(101)}},
+			},
+			want: want{code: codes.InvalidArgument},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			in := proto.Clone(tc.cfg)
+			cfg, err := tc.p.Apply(tc.cfg)
+
+			if tc.want.cfg == nil {
+				tc.want.cfg = &v1alpha1.RunFunctionConfig{}
+			}
+			if err == nil {
+				if diff := cmp.Diff(tc.want.cfg, cfg, protocmp.Transform()); diff != "" {
+					t.Errorf("\n%s\np.Apply(...): -want, +got:\n%s", tc.reason, diff)
+				}
+			}
+			if diff := cmp.Diff(tc.want.code, status.Code(err)); diff != "" {
+				t.Errorf("\n%s\np.Apply(...): -want code, +got code:\n%s\nerror: %v", tc.reason, diff, err)
+			}
+			if diff := cmp.Diff(in, tc.cfg, protocmp.Transform()); diff != "" {
+				t.Errorf("\n%s\np.Apply(...): want the supplied config to be unchanged:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
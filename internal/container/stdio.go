@@ -0,0 +1,110 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package container
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+)
+
+// Error strings.
+const (
+	errReadStdoutStream = "cannot read stdout"
+	errReadStderrStream = "cannot read stderr"
+)
+
+// Streams that may exceed their limit.
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+)
+
+// A StdioLimitError indicates that a process wrote more than the allowed
+// number of bytes to stdout or stderr.
+type StdioLimitError struct {
+	// Stream that exceeded its limit - i.e. stdout or stderr.
+	Stream string
+
+	// Limit in bytes.
+	Limit int64
+}
+
+func (e *StdioLimitError) Error() string {
+	return fmt.Sprintf("%s exceeded the limit of %d bytes", e.Stream, e.Limit)
+}
+
+// IsStdioLimitExceeded returns true if the supplied error indicates that a
+// process wrote more than the allowed number of bytes to stdout or stderr.
+func IsStdioLimitExceeded(err error) bool {
+	var e *StdioLimitError
+	return errors.As(err, &e)
+}
+
+// IsStdoutLimitExceeded returns true if the supplied error indicates that a
+// process wrote more than the allowed number of bytes to stdout. Unlike a
+// truncated stderr, a truncated stdout means the process's output is corrupt.
+func IsStdoutLimitExceeded(err error) bool {
+	var e *StdioLimitError
+	return errors.As(err, &e) && e.Stream == StreamStdout
+}
+
+// ReadStdio reads the supplied stdout and stderr until EOF. It reads them
+// concurrently, so that a process can't block writing to one while we wait
+// for the other to be closed. Each stream is limited to the supplied number of
+// bytes, unless the limit is zero. Bytes past the limit are read and
+// discarded, and a StdioLimitError is returned.
+func ReadStdio(stdout, stderr io.Reader, limit int64) ([]byte, []byte, error) {
+	type result struct {
+		b   []byte
+		err error
+	}
+	errc := make(chan result, 1)
+	go func() {
+		b, err := readLimited(stderr, limit, StreamStderr)
+		errc <- result{b: b, err: errors.Wrap(err, errReadStderrStream)}
+	}()
+
+	o, err := readLimited(stdout, limit, StreamStdout)
+	e := <-errc
+
+	if err != nil {
+		return o, e.b, errors.Wrap(err, errReadStdoutStream)
+	}
+	return o, e.b, e.err
+}
+
+func readLimited(r io.Reader, limit int64, stream string) ([]byte, error) {
+	if limit == 0 {
+		return io.ReadAll(r)
+	}
+
+	b, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return b, err
+	}
+	if int64(len(b)) <= limit {
+		return b, nil
+	}
+
+	// Drain the stream so that the writer doesn't block.
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return b[:limit], err
+	}
+	return b[:limit], &StdioLimitError{Stream: stream, Limit: limit}
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package container
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+func TestReadStdio(t *testing.T) {
+	type args struct {
+		stdout io.Reader
+		stderr io.Reader
+		limit  int64
+	}
+	type want struct {
+		stdout []byte
+		stderr []byte
+		err    error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"WithinLimit": {
+			reason: "Streams within the limit should be read in full.",
+			args: args{
+				stdout: strings.NewReader("out"),
+				stderr: strings.NewReader("err"),
+				limit:  3,
+			},
+			want: want{stdout: []byte("out"), stderr: []byte("err")},
+		},
+		"NoLimit": {
+			reason: "Streams should be read in full when there's no limit.",
+			args: args{
+				stdout: strings.NewReader("out"),
+				stderr: strings.NewReader("err"),
+			},
+			want: want{stdout: []byte("out"), stderr: []byte("err")},
+		},
+		"StdoutExceedsLimit": {
+			reason: "A stream that exceeds the limit should be truncated, and return an error.",
+			args: args{
+				stdout: strings.NewReader("outout"),
+				stderr: strings.NewReader("err"),
+				limit:  3,
+			},
+			want: want{
+				stdout: []byte("out"),
+				stderr: []byte("err"),
+				err:    errors.Wrap(&StdioLimitError{Stream: StreamStdout, Limit: 3}, errReadStdoutStream),
+			},
+		},
+		"StderrExceedsLimit": {
+			reason: "A stream that exceeds the limit should be truncated, and return an error.",
+			args: args{
+				stdout: strings.NewReader("out"),
+				stderr: strings.NewReader("errerr"),
+				limit:  3,
+			},
+			want: want{
+				stdout: []byte("out"),
+				stderr: []byte("err"),
+				err:    errors.Wrap(&StdioLimitError{Stream: StreamStderr, Limit: 3}, errReadStderrStream),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			stdout, stderr, err := ReadStdio(tc.args.stdout, tc.args.stderr, tc.args.limit)
+
+			if diff := cmp.Diff(tc.want.stdout, stdout, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("\n%s\nReadStdio(...): -want stdout, +got stdout:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.stderr, stderr, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("\n%s\nReadStdio(...): -want stderr, +got stderr:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nReadStdio(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if tc.want.err != nil && !IsStdioLimitExceeded(err) {
+				t.Errorf("\n%s\nIsStdioLimitExceeded(...): want true, got false", tc.reason)
+			}
+		})
+	}
+}
+
+func TestIsStdoutLimitExceeded(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		err    error
+		want   bool
+	}{
+		"StdoutExceeded": {
+			reason: "Exceeding the stdout limit should be reported.",
+			err:    errors.Wrap(&StdioLimitError{Stream: StreamStdout, Limit: 3}, errReadStdoutStream),
+			want:   true,
+		},
+		"StderrExceeded": {
+			reason: "Exceeding the stderr limit shouldn't be reported.",
+			err:    errors.Wrap(&StdioLimitError{Stream: StreamStderr, Limit: 3}, errReadStderrStream),
+			want:   false,
+		},
+		"OtherError": {
+			reason: "Other errors shouldn't be reported.",
+			err:    errors.New("boom"),
+			want:   false,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := IsStdoutLimitExceeded(tc.err)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nIsStdoutLimitExceeded(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestReadStdioConcurrently(t *testing.T) {
+	stdoutR, stdoutW := io.Pipe()
+	stderrR, stderrW := io.Pipe()
+
+	// Writes to an io.Pipe block until they're read. This would deadlock if
+	// ReadStdio read stdout to EOF before it started reading stderr.
+	go func() {
+		_, _ = stderrW.Write(bytes.Repeat([]byte("e"), 1<<20))
+		_ = stderrW.Close()
+		_, _ = stdoutW.Write([]byte("out"))
+		_ = stdoutW.Close()
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		stdout, stderr, err := ReadStdio(stdoutR, stderrR, 0)
+		if err != nil {
+			t.Errorf("ReadStdio(...): %v", err)
+		}
+		if diff := cmp.Diff([]byte("out"), stdout); diff != "" {
+			t.Errorf("ReadStdio(...): -want stdout, +got stdout:\n%s", diff)
+		}
+		if len(stderr) != 1<<20 {
+			t.Errorf("ReadStdio(...): want %d bytes of stderr, got %d", 1<<20, len(stderr))
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("ReadStdio(...): timed out; stdout and stderr weren't read concurrently")
+	}
+}
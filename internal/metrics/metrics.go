@@ -0,0 +1,120 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics instruments Composition Function runs.
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "function_runtime_oci"
+
+// Phases of a function run, as reported by spark.
+const (
+	PhasePull    = "pull"
+	PhaseBundle  = "bundle"
+	PhaseRuntime = "runtime"
+	PhaseCleanup = "cleanup"
+)
+
+// Results of looking an image up in the local cache.
+const (
+	CacheHit  = "hit"
+	CacheMiss = "miss"
+)
+
+// Metrics records Prometheus metrics about function runs. A nil *Metrics is
+// valid, and records nothing.
+type Metrics struct {
+	runs        *prometheus.CounterVec
+	runDuration *prometheus.HistogramVec
+	inFlight    prometheus.Gauge
+	phase       *prometheus.HistogramVec
+	cache       *prometheus.CounterVec
+	pulledBytes prometheus.Counter
+}
+
+// New returns a new Metrics, registered with the supplied registerer.
+func New(r prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		runs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "runs_total",
+			Help:      "Total number of function runs, by image and outcome.",
+		}, []string{"image", "outcome"}),
+		runDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "run_duration_seconds",
+			Help:      "Time taken to run a function, by image and outcome.",
+			Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 15, 20, 30, 60},
+		}, []string{"image", "outcome"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "runs_in_flight",
+			Help:      "Number of function runs currently in flight.",
+		}),
+		phase: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "run_phase_duration_seconds",
+			Help:      "Time spent in each phase of a function run.",
+			Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 30, 60},
+		}, []string{"phase"}),
+		cache: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "image_cache_requests_total",
+			Help:      "Total number of function image cache lookups, by result.",
+		}, []string{"result"}),
+		pulledBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "image_pulled_bytes_total",
+			Help:      "Total number of compressed image layer bytes pulled from remote registries.",
+		}),
+	}
+	r.MustRegister(m.runs, m.runDuration, m.inFlight, m.phase, m.cache, m.pulledBytes)
+	return m
+}
+
+// RunStarted records that a function run started. The returned function must
+// be called with the run's outcome once the run is done.
+func (m *Metrics) RunStarted(image string) func(outcome string) {
+	if m == nil {
+		return func(string) {}
+	}
+	m.inFlight.Inc()
+	t := time.Now()
+	return func(outcome string) {
+		m.inFlight.Dec()
+		m.runs.WithLabelValues(image, outcome).Inc()
+		m.runDuration.WithLabelValues(image, outcome).Observe(time.Since(t).Seconds())
+	}
+}
+
+// ObserveReport records the metrics reported by spark for a function run.
+func (m *Metrics) ObserveReport(r *Report) {
+	if m == nil || r == nil {
+		return
+	}
+	for phase, d := range r.Phases {
+		m.phase.WithLabelValues(phase).Observe(d.Seconds())
+	}
+	if r.Cache != "" {
+		m.cache.WithLabelValues(r.Cache).Inc()
+	}
+	m.pulledBytes.Add(float64(r.PulledBytes))
+}
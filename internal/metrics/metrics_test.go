@@ -0,0 +1,105 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestReportRoundTrip(t *testing.T) {
+	want := NewReport()
+	want.CacheMiss()
+	want.BytesPulled(42)
+	want.BytesPulled(8)
+	want.Phases[PhasePull] = 2 * time.Second
+
+	b := &bytes.Buffer{}
+	if err := want.Write(b); err != nil {
+		t.Fatalf("Write(...): %v", err)
+	}
+	got, err := ReadReport(b)
+	if err != nil {
+		t.Fatalf("ReadReport(...): %v", err)
+	}
+
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreUnexported(Report{})); diff != "" {
+		t.Errorf("ReadReport(...): -want, +got:\n%s", diff)
+	}
+}
+
+func TestReadReportLimit(t *testing.T) {
+	// A report that never ends, e.g. because spark wrote garbage.
+	b := bytes.NewBufferString(`{"phases":{"` + strings.Repeat("a", 2*MaxReportBytes) + `":1}}`)
+	if _, err := ReadReport(b); err == nil {
+		t.Errorf("ReadReport(...): want error reading a report larger than %d bytes", MaxReportBytes)
+	}
+	if b.Len() < MaxReportBytes {
+		t.Errorf("ReadReport(...): want at most %d bytes read, got %d", MaxReportBytes, 2*MaxReportBytes-b.Len())
+	}
+}
+
+func TestObserveReport(t *testing.T) {
+	m := New(prometheus.NewRegistry())
+
+	r := NewReport()
+	r.CacheHit()
+	r.BytesPulled(100)
+	r.Phases[PhaseRuntime] = time.Second
+
+	m.ObserveReport(r)
+	m.ObserveReport(r)
+
+	if got := testutil.ToFloat64(m.cache.WithLabelValues(CacheHit)); got != 2 {
+		t.Errorf("cache hits: want 2, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.pulledBytes); got != 200 {
+		t.Errorf("pulled bytes: want 200, got %v", got)
+	}
+	if got := testutil.CollectAndCount(m.phase); got != 1 {
+		t.Errorf("phase series: want 1, got %v", got)
+	}
+}
+
+func TestRunStarted(t *testing.T) {
+	m := New(prometheus.NewRegistry())
+
+	finished := m.RunStarted("cool/fn:v1")
+	if got := testutil.ToFloat64(m.inFlight); got != 1 {
+		t.Errorf("in flight: want 1, got %v", got)
+	}
+
+	finished("OK")
+	if got := testutil.ToFloat64(m.inFlight); got != 0 {
+		t.Errorf("in flight: want 0, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.runs.WithLabelValues("cool/fn:v1", "OK")); got != 1 {
+		t.Errorf("runs: want 1, got %v", got)
+	}
+
+	// A nil *Metrics should be safe to use.
+	var nilMetrics *Metrics
+	nilMetrics.RunStarted("cool/fn:v1")("OK")
+	nilMetrics.ObserveReport(NewReport())
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+)
+
+// Error strings.
+const (
+	errEncodeReport = "cannot encode run report"
+	errDecodeReport = "cannot decode run report"
+)
+
+// A Report describes a function run. spark runs in a separate process from the
+// function runner, so it can't record metrics directly. Instead it fills out a
+// Report and writes it to a pipe that the function runner reads from.
+type Report struct {
+	// Phases maps each phase of the run to the time spent in it.
+	Phases map[string]time.Duration `json:"phases,omitempty"`
+
+	// Cache is the result of looking the function's image up in the local
+	// cache.
+	Cache string `json:"cache,omitempty"`
+
+	// PulledBytes is the number of compressed layer bytes that were pulled
+	// from a remote registry.
+	PulledBytes int64 `json:"pulledBytes,omitempty"`
+
+	mx sync.Mutex
+}
+
+// NewReport returns an empty Report.
+func NewReport() *Report {
+	return &Report{Phases: make(map[string]time.Duration)}
+}
+
+// Time the supplied phase of the run. The returned function must be called
+// when the phase is done.
+func (r *Report) Time(phase string) func() {
+	t := time.Now()
+	return func() {
+		r.mx.Lock()
+		defer r.mx.Unlock()
+		r.Phases[phase] += time.Since(t)
+	}
+}
+
+// CacheHit records that the function's image was found in the local cache.
+func (r *Report) CacheHit() {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	r.Cache = CacheHit
+}
+
+// CacheMiss records that the function's image was not found in the local
+// cache.
+func (r *Report) CacheMiss() {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	r.Cache = CacheMiss
+}
+
+// BytesPulled records that the supplied number of compressed layer bytes were
+// pulled from a remote registry.
+func (r *Report) BytesPulled(n int64) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	r.PulledBytes += n
+}
+
+// Write the Report to the supplied writer.
+func (r *Report) Write(w io.Writer) error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	return errors.Wrap(json.NewEncoder(w).Encode(r), errEncodeReport)
+}
+
+// MaxReportBytes is the most ReadReport will read. A report is a handful of
+// phase timings and counters, so anything larger is garbage.
+const MaxReportBytes = 64 << 10
+
+// ReadReport reads a Report from the supplied reader. It reads at most
+// MaxReportBytes.
+func ReadReport(rd io.Reader) (*Report, error) {
+	r := NewReport()
+	if err := json.NewDecoder(io.LimitReader(rd, MaxReportBytes)).Decode(r); err != nil {
+		return nil, errors.Wrap(err, errDecodeReport)
+	}
+	return r, nil
+}
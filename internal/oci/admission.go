@@ -0,0 +1,277 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	ociv1 "github.com/google/go-containerregistry/pkg/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/yaml"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+)
+
+// Error strings.
+const (
+	errReadAdmissionPolicy  = "cannot read image admission policy"
+	errParseAdmissionPolicy = "cannot parse image admission policy"
+	errReadManifest         = "cannot read image manifest"
+	errDigestRequired       = "image must be referenced by digest"
+	errRegistryNotAllowed   = "image registry or repository is not allowed"
+
+	errFmtInvalidGlob          = "invalid glob %q"
+	errFmtRegistryDenied       = "image registry %q is denied"
+	errFmtRepositoryDenied     = "image repository %q is denied"
+	errFmtTooManyLayers        = "image has %d layers, more than the maximum of %d"
+	errFmtLayerTooLarge        = "image layer %s is %d bytes compressed, more than the maximum of %d"
+	errFmtImageTooLarge        = "image is %d bytes compressed, more than the maximum of %d"
+	errFmtUncompressedTooLarge = "image layer %s is more than the maximum of %d bytes uncompressed"
+	errFmtImageUncompressed    = "image is more than the maximum of %d bytes uncompressed"
+)
+
+// An AdmissionPolicy determines which images may be run. A zero value policy
+// admits any image.
+type AdmissionPolicy struct {
+	// Allow only images from these registries or repositories. Any image is
+	// allowed if both are empty.
+	Allow AdmissionRules `json:"allow,omitempty"`
+
+	// Deny images from these registries or repositories, even if they're
+	// allowed.
+	Deny AdmissionRules `json:"deny,omitempty"`
+
+	// RequireDigest requires images to be referenced by digest (e.g.
+	// example.org/fn@sha256:...) rather than by tag.
+	RequireDigest bool `json:"requireDigest,omitempty"`
+
+	// Limits on the size of admitted images.
+	Limits AdmissionLimits `json:"limits,omitempty"`
+}
+
+// AdmissionRules match images by registry or repository. Each is a glob, per
+// path.Match. Registries are matched against the registry host (e.g. ghcr.io)
+// and repositories against the full repository name (e.g.
+// ghcr.io/crossplane/fn). Images on Docker Hub are in the index.docker.io
+// registry.
+type AdmissionRules struct {
+	Registries   []string `json:"registries,omitempty"`
+	Repositories []string `json:"repositories,omitempty"`
+}
+
+// AdmissionLimits limit the size of admitted images. Sizes are specified in
+// Kubernetes-style resource.Quantity form. Unset limits aren't enforced.
+type AdmissionLimits struct {
+	// MaxLayers is the maximum number of layers an image may have.
+	MaxLayers int `json:"maxLayers,omitempty"`
+
+	// MaxLayerSize is the maximum compressed size of each layer.
+	MaxLayerSize *resource.Quantity `json:"maxLayerSize,omitempty"`
+
+	// MaxSize is the maximum total compressed size of an image's layers.
+	MaxSize *resource.Quantity `json:"maxSize,omitempty"`
+
+	// MaxUncompressedLayerSize is the maximum uncompressed size of each layer.
+	MaxUncompressedLayerSize *resource.Quantity `json:"maxUncompressedLayerSize,omitempty"`
+
+	// MaxUncompressedSize is the maximum total uncompressed size of an
+	// image's layers.
+	MaxUncompressedSize *resource.Quantity `json:"maxUncompressedSize,omitempty"`
+}
+
+// An admissionError indicates that an image was denied by an admission policy.
+type admissionError struct{ error }
+
+func (e admissionError) Unwrap() error { return e.error }
+
+// IsAdmissionDenied returns true if the supplied error indicates that an image
+// was denied by an admission policy.
+func IsAdmissionDenied(err error) bool {
+	return errors.As(err, &admissionError{})
+}
+
+// ReadAdmissionPolicy reads a YAML or JSON admission policy from the supplied
+// path.
+func ReadAdmissionPolicy(path string) (*AdmissionPolicy, error) {
+	b, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, errors.Wrap(err, errReadAdmissionPolicy)
+	}
+	return ParseAdmissionPolicy(b)
+}
+
+// ParseAdmissionPolicy parses a YAML or JSON admission policy. Unknown fields
+// are rejected, so that a misspelt rule isn't silently ignored.
+func ParseAdmissionPolicy(data []byte) (*AdmissionPolicy, error) {
+	p := &AdmissionPolicy{}
+	if err := yaml.UnmarshalStrict(data, p); err != nil {
+		return nil, errors.Wrap(err, errParseAdmissionPolicy)
+	}
+	for _, globs := range [][]string{p.Allow.Registries, p.Allow.Repositories, p.Deny.Registries, p.Deny.Repositories} {
+		for _, g := range globs {
+			if _, err := path.Match(g, ""); err != nil {
+				return nil, errors.Wrap(errors.Wrapf(err, errFmtInvalidGlob, g), errParseAdmissionPolicy)
+			}
+		}
+	}
+	return p, nil
+}
+
+// AdmitReference returns an error if the policy doesn't admit images with the
+// supplied reference. It should be called before pulling the image.
+func (p *AdmissionPolicy) AdmitReference(r name.Reference) error {
+	if p == nil {
+		return nil
+	}
+
+	reg, repo := r.Context().RegistryStr(), r.Context().Name()
+	if matchAny(p.Deny.Registries, reg) {
+		return admissionError{errors.Errorf(errFmtRegistryDenied, reg)}
+	}
+	if matchAny(p.Deny.Repositories, repo) {
+		return admissionError{errors.Errorf(errFmtRepositoryDenied, repo)}
+	}
+
+	restricted := len(p.Allow.Registries)+len(p.Allow.Repositories) > 0
+	if restricted && !matchAny(p.Allow.Registries, reg) && !matchAny(p.Allow.Repositories, repo) {
+		return admissionError{errors.New(errRegistryNotAllowed)}
+	}
+
+	if _, ok := r.(name.Digest); p.RequireDigest && !ok {
+		return admissionError{errors.New(errDigestRequired)}
+	}
+	return nil
+}
+
+// AdmitImage returns an error if the policy doesn't admit the supplied image,
+// per the layers listed in its manifest. Otherwise it returns the image,
+// wrapped so that reading its layers returns an error if they exceed the
+// policy's uncompressed size limits.
+func (p *AdmissionPolicy) AdmitImage(img ociv1.Image) (ociv1.Image, error) {
+	if p == nil {
+		return img, nil
+	}
+
+	m, err := img.Manifest()
+	if err != nil {
+		return nil, errors.Wrap(err, errReadManifest)
+	}
+
+	l := p.Limits
+	if l.MaxLayers > 0 && len(m.Layers) > l.MaxLayers {
+		return nil, admissionError{errors.Errorf(errFmtTooManyLayers, len(m.Layers), l.MaxLayers)}
+	}
+
+	var total int64
+	for _, desc := range m.Layers {
+		if max := value(l.MaxLayerSize); max > 0 && desc.Size > max {
+			return nil, admissionError{errors.Errorf(errFmtLayerTooLarge, desc.Digest, desc.Size, max)}
+		}
+		total += desc.Size
+	}
+	if max := value(l.MaxSize); max > 0 && total > max {
+		return nil, admissionError{errors.Errorf(errFmtImageTooLarge, total, max)}
+	}
+
+	if value(l.MaxUncompressedLayerSize) == 0 && value(l.MaxUncompressedSize) == 0 {
+		return img, nil
+	}
+	return &admittedImage{Image: img, layerMax: value(l.MaxUncompressedLayerSize), imageMax: value(l.MaxUncompressedSize), total: &atomic.Int64{}}, nil
+}
+
+func matchAny(globs []string, s string) bool {
+	for _, g := range globs {
+		// We validate globs when parsing a policy.
+		if ok, _ := path.Match(g, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func value(q *resource.Quantity) int64 {
+	if q == nil {
+		return 0
+	}
+	return q.Value()
+}
+
+// An admittedImage limits how many bytes may be read from its uncompressed
+// layers. The uncompressed size of a layer isn't known until it's read, so
+// it's enforced as its layers are pulled.
+type admittedImage struct {
+	ociv1.Image
+	layerMax int64
+	imageMax int64
+
+	// Layers may be read concurrently.
+	total *atomic.Int64
+}
+
+func (i *admittedImage) Layers() ([]ociv1.Layer, error) {
+	ls, err := i.Image.Layers()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]ociv1.Layer, len(ls))
+	for j := range ls {
+		out[j] = &admittedLayer{Layer: ls[j], image: i}
+	}
+	return out, nil
+}
+
+type admittedLayer struct {
+	ociv1.Layer
+	image *admittedImage
+}
+
+func (l *admittedLayer) Uncompressed() (io.ReadCloser, error) {
+	rc, err := l.Layer.Uncompressed()
+	if err != nil {
+		return nil, err
+	}
+	d, _ := l.Layer.Digest()
+	return &limitedReadCloser{ReadCloser: rc, layer: d, image: l.image}, nil
+}
+
+// A limitedReadCloser returns an admission error once more than the allowed
+// number of bytes have been read from its layer, or from all of its image's
+// layers.
+type limitedReadCloser struct {
+	io.ReadCloser
+	layer ociv1.Hash
+	image *admittedImage
+	read  int64
+}
+
+func (r *limitedReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.read += int64(n)
+	total := r.image.total.Add(int64(n))
+	if max := r.image.layerMax; max > 0 && r.read > max {
+		return n, admissionError{errors.Errorf(errFmtUncompressedTooLarge, r.layer, max)}
+	}
+	if max := r.image.imageMax; max > 0 && total > max {
+		return n, admissionError{errors.Errorf(errFmtImageUncompressed, max)}
+	}
+	return n, err
+}
@@ -0,0 +1,280 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-containerregistry/pkg/name"
+	ociv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestParseAdmissionPolicy(t *testing.T) {
+	type want struct {
+		p   *AdmissionPolicy
+		err bool
+	}
+
+	qty := resource.MustParse("1Mi")
+
+	cases := map[string]struct {
+		reason string
+		data   string
+		want   want
+	}{
+		"Valid": {
+			reason: "A valid policy should be parsed.",
+			data: `
+allow:
+  registries: ["ghcr.io"]
+deny:
+  repositories: ["ghcr.io/evil/*"]
+requireDigest: true
+limits:
+  maxLayers: 8
+  maxSize: 1Mi
+`,
+			want: want{p: &AdmissionPolicy{
+				Allow:         AdmissionRules{Registries: []string{"ghcr.io"}},
+				Deny:          AdmissionRules{Repositories: []string{"ghcr.io/evil/*"}},
+				RequireDigest: true,
+				Limits:        AdmissionLimits{MaxLayers: 8, MaxSize: &qty},
+			}},
+		},
+		"UnknownField": {
+			reason: "A policy with an unknown field should be rejected.",
+			data:   "requireDigests: true",
+			want:   want{err: true},
+		},
+		"InvalidGlob": {
+			reason: "A policy with an invalid glob should be rejected.",
+			data:   `allow: {registries: ["[ghcr.io"]}`,
+			want:   want{err: true},
+		},
+		"InvalidQuantity": {
+			reason: "A policy with an invalid size limit should be rejected.",
+			data:   "limits: {maxSize: lots}",
+			want:   want{err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p, err := ParseAdmissionPolicy([]byte(tc.data))
+			got := want{p: p, err: err != nil}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), cmp.Comparer(func(a, b resource.Quantity) bool { return a.Cmp(b) == 0 })); diff != "" {
+				t.Errorf("\n%s\nParseAdmissionPolicy(...): -want, +got:\n%s\nerror: %v", tc.reason, diff, err)
+			}
+		})
+	}
+}
+
+func TestAdmitReference(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		p      *AdmissionPolicy
+		ref    string
+		want   bool
+	}{
+		"NilPolicy": {
+			reason: "A nil policy should admit any image.",
+			ref:    "example.org/fn:v1",
+			want:   true,
+		},
+		"AllowedRegistry": {
+			reason: "An image from an allowed registry should be admitted.",
+			p:      &AdmissionPolicy{Allow: AdmissionRules{Registries: []string{"*.example.org"}}},
+			ref:    "cool.example.org/fn:v1",
+			want:   true,
+		},
+		"AllowedRepository": {
+			reason: "An image from an allowed repository should be admitted.",
+			p:      &AdmissionPolicy{Allow: AdmissionRules{Registries: []string{"ghcr.io"}, Repositories: []string{"example.org/crossplane/*"}}},
+			ref:    "example.org/crossplane/fn:v1",
+			want:   true,
+		},
+		"NotAllowed": {
+			reason: "An image that isn't from an allowed registry or repository should be denied.",
+			p:      &AdmissionPolicy{Allow: AdmissionRules{Registries: []string{"ghcr.io"}}},
+			ref:    "example.org/fn:v1",
+			want:   false,
+		},
+		"DockerHub": {
+			reason: "Images with no registry should be matched against Docker Hub.",
+			p:      &AdmissionPolicy{Allow: AdmissionRules{Repositories: []string{"index.docker.io/library/*"}}},
+			ref:    "fn:v1",
+			want:   true,
+		},
+		"DeniedRegistry": {
+			reason: "An image from a denied registry should be denied, even if it's allowed.",
+			p: &AdmissionPolicy{
+				Allow: AdmissionRules{Registries: []string{"*"}},
+				Deny:  AdmissionRules{Registries: []string{"example.org"}},
+			},
+			ref:  "example.org/fn:v1",
+			want: false,
+		},
+		"DeniedRepository": {
+			reason: "An image from a denied repository should be denied.",
+			p:      &AdmissionPolicy{Deny: AdmissionRules{Repositories: []string{"example.org/evil/*"}}},
+			ref:    "example.org/evil/fn:v1",
+			want:   false,
+		},
+		"DigestRequired": {
+			reason: "An image referenced by tag should be denied if digests are required.",
+			p:      &AdmissionPolicy{RequireDigest: true},
+			ref:    "example.org/fn:v1",
+			want:   false,
+		},
+		"Digest": {
+			reason: "An image referenced by digest should be admitted if digests are required.",
+			p:      &AdmissionPolicy{RequireDigest: true},
+			ref:    "example.org/fn@sha256:0000000000000000000000000000000000000000000000000000000000000000",
+			want:   true,
+		},
+	}
+
+	for n, tc := range cases {
+		t.Run(n, func(t *testing.T) {
+			r, err := name.ParseReference(tc.ref)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = tc.p.AdmitReference(r)
+			if diff := cmp.Diff(tc.want, err == nil); diff != "" {
+				t.Errorf("\n%s\nAdmitReference(...): -want admitted, +got admitted:\n%s\nerror: %v", tc.reason, diff, err)
+			}
+			if err != nil && !IsAdmissionDenied(err) {
+				t.Errorf("\n%s\nAdmitReference(...): want admission denied error, got %v", tc.reason, err)
+			}
+		})
+	}
+}
+
+func TestAdmitImage(t *testing.T) {
+	// Each layer is a tarball containing a file of 1KiB of random bytes. That's
+	// a little over 1KiB compressed, and 2.5KiB uncompressed.
+	img, err := random.Image(1024, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	qty := func(s string) *resource.Quantity {
+		q := resource.MustParse(s)
+		return &q
+	}
+
+	type want struct {
+		admitted bool
+		read     bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		l      AdmissionLimits
+		want   want
+	}{
+		"NoLimits": {
+			reason: "An image should be admitted and readable if there are no limits.",
+			want:   want{admitted: true, read: true},
+		},
+		"WithinLimits": {
+			reason: "An image within all limits should be admitted and readable.",
+			l: AdmissionLimits{
+				MaxLayers:                2,
+				MaxLayerSize:             qty("8Ki"),
+				MaxSize:                  qty("16Ki"),
+				MaxUncompressedLayerSize: qty("8Ki"),
+				MaxUncompressedSize:      qty("16Ki"),
+			},
+			want: want{admitted: true, read: true},
+		},
+		"TooManyLayers": {
+			reason: "An image with too many layers should be denied.",
+			l:      AdmissionLimits{MaxLayers: 1},
+			want:   want{admitted: false},
+		},
+		"LayerTooLarge": {
+			reason: "An image with a layer that is too large compressed should be denied.",
+			l:      AdmissionLimits{MaxLayerSize: qty("1Ki")},
+			want:   want{admitted: false},
+		},
+		"ImageTooLarge": {
+			reason: "An image that is too large compressed should be denied.",
+			l:      AdmissionLimits{MaxSize: qty("2Ki")},
+			want:   want{admitted: false},
+		},
+		"UncompressedLayerTooLarge": {
+			reason: "Reading a layer that is too large uncompressed should fail.",
+			l:      AdmissionLimits{MaxUncompressedLayerSize: qty("2Ki")},
+			want:   want{admitted: true, read: false},
+		},
+		"UncompressedImageTooLarge": {
+			reason: "Reading an image that is too large uncompressed should fail.",
+			l:      AdmissionLimits{MaxUncompressedSize: qty("4Ki")},
+			want:   want{admitted: true, read: false},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p := &AdmissionPolicy{Limits: tc.l}
+			admitted, err := p.AdmitImage(img)
+			if err != nil && !IsAdmissionDenied(err) {
+				t.Fatalf("\n%s\nAdmitImage(...): want admission denied error, got %v", tc.reason, err)
+			}
+
+			got := want{admitted: err == nil}
+			if got.admitted {
+				got.read = readLayers(t, admitted)
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nAdmitImage(...): -want, +got:\n%s\nerror: %v", tc.reason, diff, err)
+			}
+		})
+	}
+}
+
+// readLayers returns true if all of the supplied image's layers could be read,
+// and false if reading them returned an admission error.
+func readLayers(t *testing.T, img ociv1.Image) bool {
+	t.Helper()
+
+	ls, err := img.Layers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range ls {
+		rc, err := l.Uncompressed()
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = io.Copy(io.Discard, rc)
+		_ = rc.Close()
+		if IsAdmissionDenied(err) {
+			return false
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+)
+
+func TestRegistryErrors(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	type want struct {
+		notFound     bool
+		unauthorized bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		err    error
+		want   want
+	}{
+		"NotTransportError": {
+			reason: "Errors that didn't come from a registry should be neither not found nor unauthorized.",
+			err:    errBoom,
+			want:   want{},
+		},
+		"NotFoundStatus": {
+			reason: "A wrapped 404 should be not found.",
+			err:    errors.Wrap(&transport.Error{StatusCode: http.StatusNotFound}, errPullImage),
+			want:   want{notFound: true},
+		},
+		"ManifestUnknown": {
+			reason: "A MANIFEST_UNKNOWN diagnostic should be not found.",
+			err:    &transport.Error{StatusCode: http.StatusBadRequest, Errors: []transport.Diagnostic{{Code: transport.ManifestUnknownErrorCode}}},
+			want:   want{notFound: true},
+		},
+		"UnauthorizedStatus": {
+			reason: "A wrapped 401 should be unauthorized.",
+			err:    errors.Wrap(&transport.Error{StatusCode: http.StatusUnauthorized}, errPullImage),
+			want:   want{unauthorized: true},
+		},
+		"Denied": {
+			reason: "A DENIED diagnostic should be unauthorized.",
+			err:    &transport.Error{StatusCode: http.StatusBadRequest, Errors: []transport.Diagnostic{{Code: transport.DeniedErrorCode}}},
+			want:   want{unauthorized: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{notFound: IsNotFound(tc.err), unauthorized: IsUnauthorized(tc.err)}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nIsNotFound(...), IsUnauthorized(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -20,7 +20,9 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"io"
 	"net/http"
+	"runtime"
 
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
@@ -40,6 +42,8 @@ const (
 	errStoreDigest    = "cannot cache image digest"
 	errLoadImage      = "cannot load image from cache"
 	errLoadHash       = "cannot load image digest"
+	errVerifyImage    = "cannot verify image"
+	errAdmitImage     = "image denied by admission policy"
 )
 
 // An ImagePullPolicy dictates when an image may be pulled from a remote.
@@ -92,11 +96,13 @@ type ImageClientOptions struct {
 	pull      ImagePullPolicy
 	auth      *ImagePullAuth
 	transport *http.Transport
+	platform  ociv1.Platform
 }
 
 func parse(o ...ImageClientOption) ImageClientOptions {
 	opt := &ImageClientOptions{
-		pull: ImagePullPolicyIfNotPresent, // The default.
+		pull:     ImagePullPolicyIfNotPresent, // The default.
+		platform: HostPlatform(),
 	}
 	for _, fn := range o {
 		fn(opt)
@@ -121,6 +127,22 @@ func WithPullAuth(a *ImagePullAuth) ImageClientOption {
 	}
 }
 
+// WithPlatform specifies the platform against which a client should resolve
+// multi-platform image indexes. Indexes are resolved against the host platform
+// by default.
+func WithPlatform(p ociv1.Platform) ImageClientOption {
+	return func(c *ImageClientOptions) {
+		c.platform = p
+	}
+}
+
+// HostPlatform returns the platform of the host - i.e. its OS and CPU
+// architecture. The variant is left unspecified, so it matches an image for
+// any variant of the host's architecture.
+func HostPlatform() ociv1.Platform {
+	return ociv1.Platform{OS: runtime.GOOS, Architecture: runtime.GOARCH}
+}
+
 // WithCustomCA adds given root certificates to tls client configuration
 func WithCustomCA(rootCAs *x509.CertPool) ImageClientOption {
 	return func(c *ImageClientOptions) {
@@ -141,19 +163,22 @@ type ImageCache interface {
 	WriteImage(img ociv1.Image) error
 }
 
-// A HashCache maps OCI references to hashes.
+// A HashCache maps OCI references to hashes. A reference may resolve to a
+// different image on each platform, so mappings are per platform.
 type HashCache interface {
-	Hash(r name.Reference) (ociv1.Hash, error)
-	WriteHash(r name.Reference, h ociv1.Hash) error
+	Hash(r name.Reference, p ociv1.Platform) (ociv1.Hash, error)
+	WriteHash(r name.Reference, p ociv1.Platform, h ociv1.Hash) error
 }
 
 // A RemoteClient fetches OCI image manifests.
 type RemoteClient struct{}
 
 // Image fetches an image manifest. The returned image lazily pulls its layers.
+// If the reference is to an image index, Image fetches the manifest of the
+// index's image for the configured platform.
 func (i *RemoteClient) Image(ctx context.Context, ref name.Reference, o ...ImageClientOption) (ociv1.Image, error) {
 	opts := parse(o...)
-	iOpts := []remote.Option{remote.WithContext(ctx)}
+	iOpts := []remote.Option{remote.WithContext(ctx), remote.WithPlatform(opts.platform)}
 	if opts.auth != nil {
 		iOpts = append(iOpts, remote.WithAuth(opts.auth))
 	}
@@ -163,21 +188,105 @@ func (i *RemoteClient) Image(ctx context.Context, ref name.Reference, o ...Image
 	if opts.pull == ImagePullPolicyNever {
 		return nil, errors.New(errPullNever)
 	}
-	return remote.Image(ref, iOpts...)
+	desc, err := remote.Get(ref, iOpts...)
+	if err != nil {
+		return nil, err
+	}
+	img, err := desc.Image()
+	if err != nil {
+		return nil, err
+	}
+	if desc.MediaType.IsIndex() {
+		return &indexedImage{Image: img, index: desc.Digest}, nil
+	}
+	return img, nil
 }
 
+// An indexedImage is an image that was resolved from a multi-platform image
+// index.
+type indexedImage struct {
+	ociv1.Image
+
+	index ociv1.Hash
+}
+
+// PullMetrics records metrics about image pulls.
+type PullMetrics interface {
+	// CacheHit records that an image was found in the local cache.
+	CacheHit()
+
+	// CacheMiss records that an image was not found in the local cache, or
+	// that the pull policy required it to be pulled from a remote.
+	CacheMiss()
+
+	// BytesPulled records that the supplied number of compressed layer bytes
+	// were pulled from a remote.
+	BytesPulled(n int64)
+}
+
+// NopPullMetrics records no metrics.
+type NopPullMetrics struct{}
+
+// CacheHit does nothing.
+func (m NopPullMetrics) CacheHit() {}
+
+// CacheMiss does nothing.
+func (m NopPullMetrics) CacheMiss() {}
+
+// BytesPulled does nothing.
+func (m NopPullMetrics) BytesPulled(_ int64) {}
+
 // A CachingPuller pulls OCI images. Images are pulled either from a local cache
 // or a remote depending on whether they are available locally and a supplied
 // ImagePullPolicy.
 type CachingPuller struct {
-	remote  ImageClient
-	local   ImageCache
-	mapping HashCache
+	remote   ImageClient
+	local    ImageCache
+	mapping  HashCache
+	metrics  PullMetrics
+	verifier Verifier
+	policy   *AdmissionPolicy
+}
+
+// A CachingPullerOption configures a CachingPuller.
+type CachingPullerOption func(p *CachingPuller)
+
+// WithPullMetrics configures how a CachingPuller records metrics. Metrics are
+// not recorded by default.
+func WithPullMetrics(m PullMetrics) CachingPullerOption {
+	return func(p *CachingPuller) {
+		p.metrics = m
+	}
+}
+
+// WithVerifier configures a CachingPuller to verify images before returning
+// them. Images pulled from a remote are verified before their layers are
+// pulled. Images are not verified by default.
+func WithVerifier(v Verifier) CachingPullerOption {
+	return func(p *CachingPuller) {
+		p.verifier = v
+	}
+}
+
+// WithAdmissionPolicy configures a CachingPuller to only pull images from a
+// remote if they're admitted by the supplied policy. The policy's size limits
+// are checked before an image's layers are pulled, except for uncompressed
+// size limits, which are checked as layers are pulled. Layers that are already
+// cached don't count toward an image's uncompressed size. Images are admitted
+// by default.
+func WithAdmissionPolicy(p *AdmissionPolicy) CachingPullerOption {
+	return func(f *CachingPuller) {
+		f.policy = p
+	}
 }
 
 // NewCachingPuller returns an OCI image puller with a local cache.
-func NewCachingPuller(h HashCache, i ImageCache, r ImageClient) *CachingPuller {
-	return &CachingPuller{remote: r, local: i, mapping: h}
+func NewCachingPuller(h HashCache, i ImageCache, r ImageClient, o ...CachingPullerOption) *CachingPuller {
+	p := &CachingPuller{remote: r, local: i, mapping: h, metrics: NopPullMetrics{}}
+	for _, fn := range o {
+		fn(p)
+	}
+	return p
 }
 
 // Image pulls the supplied image and all of its layers. The supplied config
@@ -188,31 +297,43 @@ func (f *CachingPuller) Image(ctx context.Context, r name.Reference, o ...ImageC
 
 	switch opts.pull {
 	case ImagePullPolicyNever:
-		return f.never(r)
+		img, err := f.never(r, opts.platform)
+		if err == nil {
+			f.metrics.CacheHit()
+			return f.verified(ctx, r, img, o...)
+		}
+		return img, err
 	case ImagePullPolicyAlways:
+		f.metrics.CacheMiss()
 		return f.always(ctx, r, o...)
 	case ImagePullPolicyIfNotPresent:
 		fallthrough
 	default:
-		img, err := f.never(r)
+		img, err := f.never(r, opts.platform)
 		if err == nil {
-			return img, nil
+			f.metrics.CacheHit()
+			return f.verified(ctx, r, img, o...)
 		}
+		f.metrics.CacheMiss()
 		return f.always(ctx, r, o...)
 	}
 }
-func (f *CachingPuller) never(r name.Reference) (ociv1.Image, error) {
-	var h ociv1.Hash
-	var err error
 
-	// Avoid a cache lookup if the digest was specified explicitly.
-	switch d := r.(type) {
-	case name.Digest:
-		h, err = ociv1.NewHash(d.DigestStr())
-	default:
-		h, err = f.mapping.Hash(r)
+func (f *CachingPuller) never(r name.Reference, p ociv1.Platform) (ociv1.Image, error) {
+	// Avoid a cache lookup if the digest was specified explicitly, unless
+	// it's the digest of an image index. We only cache images, so we'll need
+	// to look up which of the index's images we resolved for this platform.
+	if d, ok := r.(name.Digest); ok {
+		h, err := ociv1.NewHash(d.DigestStr())
+		if err != nil {
+			return nil, errors.Wrap(err, errLoadHash)
+		}
+		if i, err := f.local.Image(h); err == nil {
+			return i, nil
+		}
 	}
 
+	h, err := f.mapping.Hash(r, p)
 	if err != nil {
 		return nil, errors.Wrap(err, errLoadHash)
 	}
@@ -222,14 +343,26 @@ func (f *CachingPuller) never(r name.Reference) (ociv1.Image, error) {
 }
 
 func (f *CachingPuller) always(ctx context.Context, r name.Reference, o ...ImageClientOption) (ociv1.Image, error) {
-	// This will only pull the image's manifest and config, not layers.
+	// This will only pull the image's manifest and config, not layers. If the
+	// reference is to an image index it resolves the image for our platform.
 	img, err := f.remote.Image(ctx, r, o...)
 	if err != nil {
 		return nil, errors.Wrap(err, errPullImage)
 	}
 
+	// Don't pull the layers of an image we won't run.
+	if err := f.verify(ctx, r, img, o...); err != nil {
+		return nil, err
+	}
+
+	// Don't pull the layers of an image that's too large to run.
+	admitted, err := f.policy.AdmitImage(img)
+	if err != nil {
+		return nil, errors.Wrap(err, errAdmitImage)
+	}
+
 	// This will fetch any layers that aren't already in the store.
-	if err := f.local.WriteImage(img); err != nil {
+	if err := f.local.WriteImage(&meteredImage{Image: admitted, metrics: f.metrics}); err != nil {
 		return nil, errors.Wrap(err, errStoreImage)
 	}
 
@@ -238,8 +371,10 @@ func (f *CachingPuller) always(ctx context.Context, r name.Reference, o ...Image
 		return nil, errors.Wrap(err, errImageDigest)
 	}
 
-	// Store a mapping from this reference to its digest.
-	if err := f.mapping.WriteHash(r, d); err != nil {
+	// Store a mapping from this reference to its digest. If the reference was
+	// to an image index (whether by tag or by digest) this maps it to the
+	// index's image for our platform.
+	if err := f.mapping.WriteHash(r, parse(o...).platform, d); err != nil {
 		return nil, errors.Wrap(err, errStoreDigest)
 	}
 
@@ -248,3 +383,83 @@ func (f *CachingPuller) always(ctx context.Context, r name.Reference, o ...Image
 	img, err = f.local.Image(d)
 	return img, errors.Wrap(err, errLoadImage)
 }
+
+// verified returns the supplied image if it passes verification.
+func (f *CachingPuller) verified(ctx context.Context, r name.Reference, img ociv1.Image, o ...ImageClientOption) (ociv1.Image, error) {
+	if err := f.verify(ctx, r, img, o...); err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// verify the supplied image, if the puller has a verifier.
+func (f *CachingPuller) verify(ctx context.Context, r name.Reference, img ociv1.Image, o ...ImageClientOption) error {
+	if f.verifier == nil {
+		return nil
+	}
+	d, err := img.Digest()
+	if err != nil {
+		return errors.Wrap(err, errImageDigest)
+	}
+
+	// A signature of the image index the reference resolved to verifies the
+	// image too. We know the index's digest if we just pulled it, or if the
+	// reference specified it.
+	hs := []ociv1.Hash{d}
+	if i, ok := img.(*indexedImage); ok {
+		hs = append(hs, i.index)
+	}
+	if rd, ok := r.(name.Digest); ok {
+		if h, err := ociv1.NewHash(rd.DigestStr()); err == nil && !containsHash(hs, h) {
+			hs = append(hs, h)
+		}
+	}
+	return errors.Wrap(f.verifier.Verify(ctx, r, hs, o...), errVerifyImage)
+}
+
+func containsHash(hs []ociv1.Hash, h ociv1.Hash) bool {
+	for _, e := range hs {
+		if e == h {
+			return true
+		}
+	}
+	return false
+}
+
+// A meteredImage records how many compressed layer bytes are pulled when its
+// layers are read.
+type meteredImage struct {
+	ociv1.Image
+	metrics PullMetrics
+}
+
+func (i *meteredImage) Layers() ([]ociv1.Layer, error) {
+	ls, err := i.Image.Layers()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]ociv1.Layer, len(ls))
+	for j := range ls {
+		out[j] = &meteredLayer{Layer: ls[j], metrics: i.metrics}
+	}
+	return out, nil
+}
+
+// A meteredLayer records its compressed size when it is read. Layers that are
+// already cached are never read, so this approximates the number of bytes
+// pulled from the remote.
+type meteredLayer struct {
+	ociv1.Layer
+	metrics PullMetrics
+}
+
+func (l *meteredLayer) Uncompressed() (io.ReadCloser, error) {
+	rc, err := l.Layer.Uncompressed()
+	if err != nil {
+		return nil, err
+	}
+	if n, err := l.Layer.Size(); err == nil {
+		l.metrics.BytesPulled(n)
+	}
+	return rc, nil
+}
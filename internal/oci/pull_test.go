@@ -19,11 +19,20 @@ package oci
 import (
 	"context"
 	"crypto/x509"
+	"io"
+	"log"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
 	ociv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
 
 	"github.com/crossplane/crossplane-runtime/pkg/errors"
 	"github.com/crossplane/crossplane-runtime/pkg/test"
@@ -32,10 +41,12 @@ import (
 type MockImage struct {
 	ociv1.Image
 
-	MockDigest func() (ociv1.Hash, error)
+	MockDigest   func() (ociv1.Hash, error)
+	MockManifest func() (*ociv1.Manifest, error)
 }
 
-func (i *MockImage) Digest() (ociv1.Hash, error) { return i.MockDigest() }
+func (i *MockImage) Digest() (ociv1.Hash, error)        { return i.MockDigest() }
+func (i *MockImage) Manifest() (*ociv1.Manifest, error) { return i.MockManifest() }
 
 type MockImageClient struct {
 	MockImage func(ctx context.Context, ref name.Reference, o ...ImageClientOption) (ociv1.Image, error)
@@ -59,16 +70,24 @@ func (c *MockImageCache) WriteImage(img ociv1.Image) error {
 }
 
 type MockHashCache struct {
-	MockHash      func(r name.Reference) (ociv1.Hash, error)
-	MockWriteHash func(r name.Reference, h ociv1.Hash) error
+	MockHash      func(r name.Reference, p ociv1.Platform) (ociv1.Hash, error)
+	MockWriteHash func(r name.Reference, p ociv1.Platform, h ociv1.Hash) error
 }
 
-func (c *MockHashCache) Hash(r name.Reference) (ociv1.Hash, error) {
-	return c.MockHash(r)
+func (c *MockHashCache) Hash(r name.Reference, p ociv1.Platform) (ociv1.Hash, error) {
+	return c.MockHash(r, p)
 }
 
-func (c *MockHashCache) WriteHash(r name.Reference, h ociv1.Hash) error {
-	return c.MockWriteHash(r, h)
+func (c *MockHashCache) WriteHash(r name.Reference, p ociv1.Platform, h ociv1.Hash) error {
+	return c.MockWriteHash(r, p, h)
+}
+
+type MockVerifier struct {
+	MockVerify func(ctx context.Context, r name.Reference, hs []ociv1.Hash, o ...ImageClientOption) error
+}
+
+func (v *MockVerifier) Verify(ctx context.Context, r name.Reference, hs []ociv1.Hash, o ...ImageClientOption) error {
+	return v.MockVerify(ctx, r, hs, o...)
 }
 
 func TestImage(t *testing.T) {
@@ -95,7 +114,7 @@ func TestImage(t *testing.T) {
 			reason: "We should return an error if we must but can't read a hash from our HashStore.",
 			p: NewCachingPuller(
 				&MockHashCache{
-					MockHash: func(r name.Reference) (ociv1.Hash, error) { return ociv1.Hash{}, errBoom },
+					MockHash: func(r name.Reference, p ociv1.Platform) (ociv1.Hash, error) { return ociv1.Hash{}, errBoom },
 				},
 				&MockImageCache{},
 				&MockImageClient{},
@@ -111,7 +130,7 @@ func TestImage(t *testing.T) {
 			reason: "We should return an error if we must but can't read our image from cache.",
 			p: NewCachingPuller(
 				&MockHashCache{
-					MockHash: func(r name.Reference) (ociv1.Hash, error) { return ociv1.Hash{}, nil },
+					MockHash: func(r name.Reference, p ociv1.Platform) (ociv1.Hash, error) { return ociv1.Hash{}, nil },
 				},
 				&MockImageCache{
 					MockImage: func(h ociv1.Hash) (ociv1.Image, error) { return nil, errBoom },
@@ -129,7 +148,7 @@ func TestImage(t *testing.T) {
 			reason: "We should return our image from cache.",
 			p: NewCachingPuller(
 				&MockHashCache{
-					MockHash: func(r name.Reference) (ociv1.Hash, error) { return ociv1.Hash{}, nil },
+					MockHash: func(r name.Reference, p ociv1.Platform) (ociv1.Hash, error) { return ociv1.Hash{}, nil },
 				},
 				&MockImageCache{
 					MockImage: func(h ociv1.Hash) (ociv1.Image, error) { return coolImage, nil },
@@ -165,6 +184,35 @@ func TestImage(t *testing.T) {
 				i: coolImage,
 			},
 		},
+		"NeverPullSuccessIndexDigest": {
+			reason: "We should return our image from cache using the digest it was resolved to for our platform if the digest of an image index was specified explicitly.",
+			p: NewCachingPuller(
+				&MockHashCache{
+					MockHash: func(r name.Reference, p ociv1.Platform) (ociv1.Hash, error) {
+						if p.Architecture != "arm64" {
+							return ociv1.Hash{}, errors.New("unexpected platform")
+						}
+						return ociv1.Hash{Algorithm: "sha256", Hex: "cool"}, nil
+					},
+				},
+				&MockImageCache{
+					MockImage: func(h ociv1.Hash) (ociv1.Image, error) {
+						if h.Hex != "cool" {
+							return nil, errors.New("image not found")
+						}
+						return coolImage, nil
+					},
+				},
+				&MockImageClient{},
+			),
+			args: args{
+				r: name.MustParseReference("example.org/coolimage@sha256:c34045c1a1db8d1b3fca8a692198466952daae07eaf6104b4c87ed3b55b6af1b"),
+				o: []ImageClientOption{WithPullPolicy(ImagePullPolicyNever), WithPlatform(ociv1.Platform{OS: "linux", Architecture: "arm64"})},
+			},
+			want: want{
+				i: coolImage,
+			},
+		},
 		"AlwaysPullRemoteError": {
 			reason: "We should return an error if we must but can't pull our image manifest from the remote.",
 			p: NewCachingPuller(
@@ -225,11 +273,63 @@ func TestImage(t *testing.T) {
 				err: errors.Wrap(errBoom, errImageDigest),
 			},
 		},
+		"AlwaysPullVerifyError": {
+			reason: "We should return an error, without caching the image, if it fails verification.",
+			p: NewCachingPuller(
+				&MockHashCache{},
+				&MockImageCache{
+					MockWriteImage: func(img ociv1.Image) error { return errors.New("image should not be cached") },
+				},
+				&MockImageClient{
+					MockImage: func(ctx context.Context, ref name.Reference, o ...ImageClientOption) (ociv1.Image, error) {
+						return &MockImage{
+							MockDigest: func() (ociv1.Hash, error) { return ociv1.Hash{}, nil },
+						}, nil
+					},
+				},
+				WithVerifier(&MockVerifier{
+					MockVerify: func(_ context.Context, _ name.Reference, _ []ociv1.Hash, _ ...ImageClientOption) error {
+						return errBoom
+					},
+				}),
+			),
+			args: args{
+				o: []ImageClientOption{WithPullPolicy(ImagePullPolicyAlways)},
+			},
+			want: want{
+				err: errors.Wrap(errBoom, errVerifyImage),
+			},
+		},
+		"AlwaysPullAdmissionDenied": {
+			reason: "We should return an error, without caching the image, if it isn't admitted by the admission policy.",
+			p: NewCachingPuller(
+				&MockHashCache{},
+				&MockImageCache{
+					MockWriteImage: func(img ociv1.Image) error { return errors.New("image should not be cached") },
+				},
+				&MockImageClient{
+					MockImage: func(ctx context.Context, ref name.Reference, o ...ImageClientOption) (ociv1.Image, error) {
+						return &MockImage{
+							MockManifest: func() (*ociv1.Manifest, error) {
+								return &ociv1.Manifest{Layers: []ociv1.Descriptor{{}, {}}}, nil
+							},
+						}, nil
+					},
+				},
+				WithAdmissionPolicy(&AdmissionPolicy{Limits: AdmissionLimits{MaxLayers: 1}}),
+			),
+			args: args{
+				o: []ImageClientOption{WithPullPolicy(ImagePullPolicyAlways)},
+			},
+			want: want{
+				err: errors.Wrap(admissionError{errors.Errorf(errFmtTooManyLayers, 2, 1)}, errAdmitImage),
+			},
+		},
 		"AlwaysPullWriteDigestError": {
 			reason: "We should return an error if we can't write our digest mapping to the cache.",
 			p: NewCachingPuller(
 				&MockHashCache{
-					MockWriteHash: func(r name.Reference, h ociv1.Hash) error { return errBoom },
+					MockWriteHash: func(r name.Reference, p ociv1.Platform, h ociv1.Hash) error { return errBoom },
 				},
 				&MockImageCache{
 					MockWriteImage: func(img ociv1.Image) error { return nil },
@@ -253,7 +353,7 @@ func TestImage(t *testing.T) {
 			reason: "We should return an error if we must but can't read our image back from cache.",
 			p: NewCachingPuller(
 				&MockHashCache{
-					MockWriteHash: func(r name.Reference, h ociv1.Hash) error { return nil },
+					MockWriteHash: func(r name.Reference, p ociv1.Platform, h ociv1.Hash) error { return nil },
 				},
 				&MockImageCache{
 					MockWriteImage: func(img ociv1.Image) error { return nil },
@@ -278,7 +378,7 @@ func TestImage(t *testing.T) {
 			reason: "We should return a pulled and cached image.",
 			p: NewCachingPuller(
 				&MockHashCache{
-					MockWriteHash: func(r name.Reference, h ociv1.Hash) error { return nil },
+					MockWriteHash: func(r name.Reference, p ociv1.Platform, h ociv1.Hash) error { return nil },
 				},
 				&MockImageCache{
 					MockWriteImage: func(img ociv1.Image) error { return nil },
@@ -303,10 +403,10 @@ func TestImage(t *testing.T) {
 			reason: "We should return a pulled and cached image.",
 			p: NewCachingPuller(
 				&MockHashCache{
-					MockHash: func(r name.Reference) (ociv1.Hash, error) {
+					MockHash: func(r name.Reference, p ociv1.Platform) (ociv1.Hash, error) {
 						return ociv1.Hash{}, errors.New("this error should not be returned")
 					},
-					MockWriteHash: func(r name.Reference, h ociv1.Hash) error {
+					MockWriteHash: func(r name.Reference, p ociv1.Platform, h ociv1.Hash) error {
 						return nil
 					},
 				},
@@ -341,7 +441,7 @@ func TestImage(t *testing.T) {
 			reason: "The IfNotPresent policy should try to read from cache first.",
 			p: NewCachingPuller(
 				&MockHashCache{
-					MockHash: func(r name.Reference) (ociv1.Hash, error) { return ociv1.Hash{}, nil },
+					MockHash: func(r name.Reference, p ociv1.Platform) (ociv1.Hash, error) { return ociv1.Hash{}, nil },
 				},
 				&MockImageCache{
 					MockImage: func(h ociv1.Hash) (ociv1.Image, error) { return &MockImage{}, nil },
@@ -364,7 +464,7 @@ func TestImage(t *testing.T) {
 			reason: "The IfNotPresent policy should fall back to pulling from the remote if it can't read the image from cache.",
 			p: NewCachingPuller(
 				&MockHashCache{
-					MockHash: func(r name.Reference) (ociv1.Hash, error) {
+					MockHash: func(r name.Reference, p ociv1.Platform) (ociv1.Hash, error) {
 						// Trigger a fall-back from never to always.
 						return ociv1.Hash{}, errors.New("this error should not be returned")
 					},
@@ -400,3 +500,71 @@ func TestImage(t *testing.T) {
 	}
 
 }
+
+func TestRemoteClientImage(t *testing.T) {
+	srv := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	amd64, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	arm64, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx := mutate.AppendManifests(empty.Index,
+		mutate.IndexAddendum{Add: amd64, Descriptor: ociv1.Descriptor{Platform: &ociv1.Platform{OS: "linux", Architecture: "amd64"}}},
+		mutate.IndexAddendum{Add: arm64, Descriptor: ociv1.Descriptor{Platform: &ociv1.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"}}},
+	)
+	ref, err := name.ParseReference(host + "/fn:v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.WriteIndex(ref, idx); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]struct {
+		reason   string
+		platform ociv1.Platform
+		want     ociv1.Image
+	}{
+		"AMD64": {
+			reason:   "An index should be resolved to the image for the requested platform.",
+			platform: ociv1.Platform{OS: "linux", Architecture: "amd64"},
+			want:     amd64,
+		},
+		"ARM64": {
+			reason:   "An index should be resolved to the image for the requested platform, regardless of its variant.",
+			platform: ociv1.Platform{OS: "linux", Architecture: "arm64"},
+			want:     arm64,
+		},
+		"ARM64V8": {
+			reason:   "An index should be resolved to the image for the requested platform and variant.",
+			platform: ociv1.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"},
+			want:     arm64,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			img, err := (&RemoteClient{}).Image(context.Background(), ref, WithPlatform(tc.platform))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := tc.want.Digest()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := img.Digest()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("\n%s\nImage(...): -want digest, +got digest:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spec
+
+import (
+	"encoding/json"
+	"os"
+	goruntime "runtime"
+
+	runtime "github.com/opencontainers/runtime-spec/specs-go"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+)
+
+// Seccomp profiles that may be passed to WithSeccompProfileName in lieu of a
+// path.
+const (
+	SeccompProfileDefault    = "default"
+	SeccompProfileUnconfined = "unconfined"
+)
+
+const (
+	errReadSeccompProfile  = "cannot read seccomp profile"
+	errParseSeccompProfile = "cannot parse seccomp profile"
+)
+
+// Errno values returned by the default seccomp profile.
+const (
+	errnoEPERM  = 1
+	errnoENOSYS = 38
+)
+
+// Namespace flags that may not be passed to clone(2). Only CAP_SYS_ADMIN may
+// create namespaces, and function containers never have it.
+const cloneNamespaceFlags = 0x7E020000
+
+// Address families that may not be passed to socket(2).
+const afVsock = 40
+
+// Syscalls that the default seccomp profile always allows. Names that aren't
+// known on a particular architecture are ignored by the OCI runtime.
+var defaultSyscalls = []string{
+	"accept", "accept4", "access", "adjtimex", "alarm", "arch_prctl", "arm_fadvise64_64",
+	"arm_sync_file_range", "bind", "breakpoint", "brk", "cacheflush", "capget", "capset",
+	"chdir", "chmod", "chown", "chown32", "clock_adjtime", "clock_adjtime64",
+	"clock_getres", "clock_getres_time64", "clock_gettime", "clock_gettime64",
+	"clock_nanosleep", "clock_nanosleep_time64", "close", "close_range", "connect",
+	"copy_file_range", "creat", "dup", "dup2", "dup3", "epoll_create", "epoll_create1",
+	"epoll_ctl", "epoll_ctl_old", "epoll_pwait", "epoll_pwait2", "epoll_wait",
+	"epoll_wait_old", "eventfd", "eventfd2", "execve", "execveat", "exit", "exit_group",
+	"faccessat", "faccessat2", "fadvise64", "fadvise64_64", "fallocate", "fanotify_mark",
+	"fchdir", "fchmod", "fchmodat", "fchmodat2", "fchown", "fchown32", "fchownat", "fcntl",
+	"fcntl64", "fdatasync", "fgetxattr", "flistxattr", "flock", "fork", "fremovexattr",
+	"fsetxattr", "fstat", "fstat64", "fstatat64", "fstatfs", "fstatfs64", "fsync",
+	"ftruncate", "ftruncate64", "futex", "futex_requeue", "futex_time64", "futex_wait",
+	"futex_waitv", "futex_wake", "futimesat", "get_robust_list", "get_thread_area",
+	"getcpu", "getcwd", "getdents", "getdents64", "getegid", "getegid32", "geteuid",
+	"geteuid32", "getgid", "getgid32", "getgroups", "getgroups32", "getitimer",
+	"getpeername", "getpgid", "getpgrp", "getpid", "getppid", "getpriority", "getrandom",
+	"getresgid", "getresgid32", "getresuid", "getresuid32", "getrlimit", "getrusage",
+	"getsid", "getsockname", "getsockopt", "gettid", "gettimeofday", "getuid", "getuid32",
+	"getxattr", "inotify_add_watch", "inotify_init", "inotify_init1", "inotify_rm_watch",
+	"io_cancel", "io_destroy", "io_getevents", "io_pgetevents", "io_pgetevents_time64",
+	"io_setup", "io_submit", "ioctl", "ioprio_get", "ioprio_set", "ipc", "kill",
+	"landlock_add_rule", "landlock_create_ruleset", "landlock_restrict_self", "lchown",
+	"lchown32", "lgetxattr", "link", "linkat", "listen", "listxattr", "llistxattr",
+	"_llseek", "lremovexattr", "lseek", "lsetxattr", "lstat", "lstat64", "madvise",
+	"membarrier", "memfd_create", "memfd_secret", "mincore", "mkdir", "mkdirat", "mknod",
+	"mknodat", "mlock", "mlock2", "mlockall", "mmap", "mmap2", "modify_ldt", "mprotect",
+	"mq_getsetattr", "mq_notify", "mq_open", "mq_timedreceive", "mq_timedreceive_time64",
+	"mq_timedsend", "mq_timedsend_time64", "mq_unlink", "mremap", "msgctl", "msgget",
+	"msgrcv", "msgsnd", "msync", "munlock", "munlockall", "munmap", "name_to_handle_at",
+	"nanosleep", "newfstatat", "_newselect", "open", "openat", "openat2", "pause",
+	"pidfd_open", "pidfd_send_signal", "pipe", "pipe2", "pkey_alloc", "pkey_free",
+	"pkey_mprotect", "poll", "ppoll", "ppoll_time64", "prctl", "pread64", "preadv",
+	"preadv2", "prlimit64", "process_mrelease", "pselect6", "pselect6_time64", "pwrite64",
+	"pwritev", "pwritev2", "read", "readahead", "readlink", "readlinkat", "readv", "recv",
+	"recvfrom", "recvmmsg", "recvmmsg_time64", "recvmsg", "remap_file_pages",
+	"removexattr", "rename", "renameat", "renameat2", "restart_syscall", "rmdir", "rseq",
+	"rt_sigaction", "rt_sigpending", "rt_sigprocmask", "rt_sigqueueinfo", "rt_sigreturn",
+	"rt_sigsuspend", "rt_sigtimedwait", "rt_sigtimedwait_time64", "rt_tgsigqueueinfo",
+	"sched_get_priority_max", "sched_get_priority_min", "sched_getaffinity",
+	"sched_getattr", "sched_getparam", "sched_getscheduler", "sched_rr_get_interval",
+	"sched_rr_get_interval_time64", "sched_setaffinity", "sched_setattr",
+	"sched_setparam", "sched_setscheduler", "sched_yield", "seccomp", "select", "semctl",
+	"semget", "semop", "semtimedop", "semtimedop_time64", "send", "sendfile", "sendfile64",
+	"sendmmsg", "sendmsg", "sendto", "set_robust_list", "set_thread_area",
+	"set_tid_address", "set_tls", "setfsgid", "setfsgid32", "setfsuid", "setfsuid32",
+	"setgid", "setgid32", "setgroups", "setgroups32", "setitimer", "setpgid",
+	"setpriority", "setregid", "setregid32", "setresgid", "setresgid32", "setresuid",
+	"setresuid32", "setreuid", "setreuid32", "setrlimit", "setsid", "setsockopt",
+	"setuid", "setuid32", "setxattr", "shmat", "shmctl", "shmdt", "shmget", "shutdown",
+	"sigaltstack", "signalfd", "signalfd4", "sigprocmask", "sigreturn", "socketcall",
+	"socketpair", "splice", "stat", "stat64", "statfs", "statfs64", "statx", "symlink",
+	"symlinkat", "sync", "sync_file_range", "sync_file_range2", "syncfs", "sysinfo", "tee",
+	"tgkill", "time", "timer_create", "timer_delete", "timer_getoverrun",
+	"timer_gettime", "timer_gettime64", "timer_settime", "timer_settime64",
+	"timerfd_create", "timerfd_gettime", "timerfd_gettime64", "timerfd_settime",
+	"timerfd_settime64", "times", "tkill", "truncate", "truncate64", "ugetrlimit", "umask",
+	"uname", "unlink", "unlinkat", "utime", "utimensat", "utimensat_time64", "utimes",
+	"vfork", "vmsplice", "wait4", "waitid", "waitpid", "write", "writev",
+}
+
+// DefaultSeccompProfile returns the default seccomp profile for function
+// containers. It's modeled on the default profile used by Docker and
+// containerd for containers without CAP_SYS_ADMIN. It allows the syscalls
+// most programs need, and returns EPERM for any others.
+func DefaultSeccompProfile() *runtime.LinuxSeccomp {
+	eperm := uint(errnoEPERM)
+	enosys := uint(errnoENOSYS)
+
+	return &runtime.LinuxSeccomp{
+		DefaultAction:   runtime.ActErrno,
+		DefaultErrnoRet: &eperm,
+		Architectures:   seccompArchitectures(goruntime.GOARCH),
+		Syscalls: []runtime.LinuxSyscall{
+			{
+				Names:  defaultSyscalls,
+				Action: runtime.ActAllow,
+			},
+			{
+				// Allow all sockets except vsock, which can reach the host.
+				Names:  []string{"socket"},
+				Action: runtime.ActAllow,
+				Args:   []runtime.LinuxSeccompArg{{Index: 0, Value: afVsock, Op: runtime.OpNotEqual}},
+			},
+			{
+				// Allow only the personalities that are known to be safe.
+				Names:  []string{"personality"},
+				Action: runtime.ActAllow,
+				Args:   []runtime.LinuxSeccompArg{{Index: 0, Value: 0x0, Op: runtime.OpEqualTo}},
+			},
+			{
+				Names:  []string{"personality"},
+				Action: runtime.ActAllow,
+				Args:   []runtime.LinuxSeccompArg{{Index: 0, Value: 0x0008, Op: runtime.OpEqualTo}},
+			},
+			{
+				Names:  []string{"personality"},
+				Action: runtime.ActAllow,
+				Args:   []runtime.LinuxSeccompArg{{Index: 0, Value: 0x20000, Op: runtime.OpEqualTo}},
+			},
+			{
+				Names:  []string{"personality"},
+				Action: runtime.ActAllow,
+				Args:   []runtime.LinuxSeccompArg{{Index: 0, Value: 0x20008, Op: runtime.OpEqualTo}},
+			},
+			{
+				Names:  []string{"personality"},
+				Action: runtime.ActAllow,
+				Args:   []runtime.LinuxSeccompArg{{Index: 0, Value: 0xffffffff, Op: runtime.OpEqualTo}},
+			},
+			{
+				// Allow clone, but not to create new namespaces.
+				Names:  []string{"clone"},
+				Action: runtime.ActAllow,
+				Args:   []runtime.LinuxSeccompArg{{Index: 0, Value: cloneNamespaceFlags, ValueTwo: 0, Op: runtime.OpMaskedEqual}},
+			},
+			{
+				// We can't filter clone3's flags, which are passed in a
+				// struct. Returning ENOSYS makes libc fall back to clone.
+				Names:    []string{"clone3"},
+				Action:   runtime.ActErrno,
+				ErrnoRet: &enosys,
+			},
+		},
+	}
+}
+
+// seccompArchitectures returns the architectures a seccomp profile should
+// apply to on the supplied GOARCH, including any that binaries built for it
+// may also use. It returns nil if only the native architecture applies.
+func seccompArchitectures(goarch string) []runtime.Arch {
+	switch goarch {
+	case "amd64":
+		return []runtime.Arch{runtime.ArchX86_64, runtime.ArchX86, runtime.ArchX32}
+	case "arm64":
+		return []runtime.Arch{runtime.ArchAARCH64, runtime.ArchARM}
+	}
+	return nil
+}
+
+// WithSeccompProfile configures the container to use the seccomp profile at
+// the supplied path. The profile must be in the format of the linux.seccomp
+// object of an OCI runtime spec.
+func WithSeccompProfile(path string) Option {
+	return func(s *runtime.Spec) error {
+		b, err := os.ReadFile(path) //nolint:gosec // Reading a variable path is intentional.
+		if err != nil {
+			return errors.Wrap(err, errReadSeccompProfile)
+		}
+		sc := &runtime.LinuxSeccomp{}
+		if err := json.Unmarshal(b, sc); err != nil {
+			return errors.Wrap(err, errParseSeccompProfile)
+		}
+		if s.Linux == nil {
+			s.Linux = &runtime.Linux{}
+		}
+		s.Linux.Seccomp = sc
+		return nil
+	}
+}
+
+// WithSeccompProfileName configures the container to use the named seccomp
+// profile. The name may be SeccompProfileDefault, SeccompProfileUnconfined, or
+// the path to a profile per WithSeccompProfile. The default profile is used
+// if the name is empty.
+func WithSeccompProfileName(name string) Option {
+	switch name {
+	case "", SeccompProfileDefault:
+		return func(s *runtime.Spec) error {
+			if s.Linux == nil {
+				s.Linux = &runtime.Linux{}
+			}
+			s.Linux.Seccomp = DefaultSeccompProfile()
+			return nil
+		}
+	case SeccompProfileUnconfined:
+		return WithoutSeccomp()
+	}
+	return WithSeccompProfile(name)
+}
+
+// WithoutSeccomp configures the container to run without a seccomp profile,
+// i.e. unconfined.
+func WithoutSeccomp() Option {
+	return func(s *runtime.Spec) error {
+		if s.Linux != nil {
+			s.Linux.Seccomp = nil
+		}
+		return nil
+	}
+}
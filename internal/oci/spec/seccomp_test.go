@@ -0,0 +1,105 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spec
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	runtime "github.com/opencontainers/runtime-spec/specs-go"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+func TestWithSeccompProfile(t *testing.T) {
+	tmp := t.TempDir()
+
+	profile := &runtime.LinuxSeccomp{
+		DefaultAction: runtime.ActErrno,
+		Syscalls:      []runtime.LinuxSyscall{{Names: []string{"read", "write"}, Action: runtime.ActAllow}},
+	}
+	b, _ := json.Marshal(profile)
+	valid := filepath.Join(tmp, "valid.json")
+	_ = os.WriteFile(valid, b, 0o600)
+
+	invalid := filepath.Join(tmp, "invalid.json")
+	_ = os.WriteFile(invalid, []byte("{"), 0o600)
+
+	type want struct {
+		s   *runtime.Spec
+		err error
+	}
+
+	cases := map[string]struct {
+		reason string
+		path   string
+		want   want
+	}{
+		"ValidProfile": {
+			reason: "We should replace the spec's seccomp profile with the supplied one.",
+			path:   valid,
+			want: want{
+				s: &runtime.Spec{Linux: &runtime.Linux{Seccomp: profile}},
+			},
+		},
+		"InvalidProfile": {
+			reason: "We should return an error if the profile isn't valid JSON.",
+			path:   invalid,
+			want: want{
+				s:   &runtime.Spec{Linux: &runtime.Linux{Seccomp: DefaultSeccompProfile()}},
+				err: errors.Wrap(errors.New("unexpected end of JSON input"), errParseSeccompProfile),
+			},
+		},
+		"MissingProfile": {
+			reason: "We should return an error if the profile doesn't exist.",
+			path:   filepath.Join(tmp, "missing.json"),
+			want: want{
+				s:   &runtime.Spec{Linux: &runtime.Linux{Seccomp: DefaultSeccompProfile()}},
+				err: errors.Wrap(errors.Errorf("open %s: no such file or directory", filepath.Join(tmp, "missing.json")), errReadSeccompProfile),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := &runtime.Spec{Linux: &runtime.Linux{Seccomp: DefaultSeccompProfile()}}
+			err := WithSeccompProfile(tc.path)(s)
+
+			if diff := cmp.Diff(tc.want.s, s, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("\n%s\nWithSeccompProfile(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nWithSeccompProfile(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestWithoutSeccomp(t *testing.T) {
+	s := &runtime.Spec{Linux: &runtime.Linux{Seccomp: DefaultSeccompProfile()}}
+	if err := WithoutSeccomp()(s); err != nil {
+		t.Errorf("WithoutSeccomp(...): %v", err)
+	}
+	if diff := cmp.Diff(&runtime.Spec{Linux: &runtime.Linux{}}, s); diff != "" {
+		t.Errorf("WithoutSeccomp(...): -want, +got:\n%s", diff)
+	}
+}
@@ -22,11 +22,13 @@ import (
 	"encoding/json"
 	"io"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 
 	ociv1 "github.com/google/go-containerregistry/pkg/v1"
 	runtime "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/opencontainers/runtime-spec/specs-go/features"
 	"k8s.io/apimachinery/pkg/api/resource"
 
 	"github.com/crossplane/crossplane-runtime/pkg/errors"
@@ -48,12 +50,31 @@ const (
 	errOpenPasswdFile   = "cannot open passwd file"
 	errOpenGroupFile    = "cannot open group file"
 	errParsePasswdFiles = "cannot parse container's /etc/passwd and/or /etc/group files"
+	errSeccompFeatures  = "OCI runtime cannot apply the seccomp profile"
+	errNoSeccomp        = "OCI runtime doesn't support seccomp"
 
+	errFmtInvalidEnvName   = "invalid environment variable name %q"
+	errFmtNoNamespace      = "OCI runtime doesn't support %s namespaces"
+	errFmtNoSeccompAction  = "OCI runtime doesn't support seccomp action %q"
+	errFmtNoSeccompOp      = "OCI runtime doesn't support seccomp operator %q"
 	errFmtTooManyColons    = "cannot parse user %q (too many colon separators)"
 	errFmtNonExistentUser  = "cannot resolve UID of user %q that doesn't exist in container's /etc/passwd"
 	errFmtNonExistentGroup = "cannot resolve GID of group %q that doesn't exist in container's /etc/group"
 )
 
+// Limits applied to a new spec.
+const (
+	DefaultPidsLimit      = 32768
+	DefaultOpenFilesLimit = 1024
+)
+
+// Process rlimits.
+const (
+	RlimitOpenFiles    = "RLIMIT_NOFILE"
+	RlimitProcesses    = "RLIMIT_NPROC"
+	RlimitCoreFileSize = "RLIMIT_CORE"
+)
+
 // An Option specifies optional OCI runtime configuration.
 type Option func(s *runtime.Spec) error
 
@@ -90,9 +111,9 @@ func New(o ...Option) (*runtime.Spec, error) {
 			},
 			Rlimits: []runtime.POSIXRlimit{
 				{
-					Type: "RLIMIT_NOFILE",
-					Hard: 1024,
-					Soft: 1024,
+					Type: RlimitOpenFiles,
+					Hard: DefaultOpenFilesLimit,
+					Soft: DefaultOpenFilesLimit,
 				},
 			},
 		},
@@ -142,8 +163,8 @@ func New(o ...Option) (*runtime.Spec, error) {
 				Options:     []string{"rprivate", "nosuid", "noexec", "nodev", "relatime", "ro"},
 			},
 		},
-		// TODO(negz): Do we need a seccomp policy? Our host probably has one.
 		Linux: &runtime.Linux{
+			Seccomp: DefaultSeccompProfile(),
 			Resources: &runtime.LinuxResources{
 				Devices: []runtime.LinuxDeviceCgroup{
 					{
@@ -152,7 +173,7 @@ func New(o ...Option) (*runtime.Spec, error) {
 					},
 				},
 				Pids: &runtime.LinuxPids{
-					Limit: 32768,
+					Limit: DefaultPidsLimit,
 				},
 			},
 			Namespaces: []runtime.LinuxNamespace{
@@ -272,6 +293,50 @@ func WithMemoryLimit(limit string) Option {
 	}
 }
 
+// WithPidsLimit limits the number of processes and threads in the container.
+func WithPidsLimit(limit int64) Option {
+	return func(s *runtime.Spec) error {
+		if s.Linux == nil {
+			s.Linux = &runtime.Linux{}
+		}
+		if s.Linux.Resources == nil {
+			s.Linux.Resources = &runtime.LinuxResources{}
+		}
+		s.Linux.Resources.Pids = &runtime.LinuxPids{Limit: limit}
+		return nil
+	}
+}
+
+// WithRlimit sets both the soft and hard value of the supplied rlimit type
+// (e.g. RLIMIT_NOFILE) for the container's process.
+func WithRlimit(typ string, limit uint64) Option {
+	return func(s *runtime.Spec) error {
+		if s.Process == nil {
+			s.Process = &runtime.Process{}
+		}
+		for i := range s.Process.Rlimits {
+			if s.Process.Rlimits[i].Type == typ {
+				s.Process.Rlimits[i].Soft = limit
+				s.Process.Rlimits[i].Hard = limit
+				return nil
+			}
+		}
+		s.Process.Rlimits = append(s.Process.Rlimits, runtime.POSIXRlimit{Type: typ, Soft: limit, Hard: limit})
+		return nil
+	}
+}
+
+// WithoutCapabilities drops all of the container's capabilities.
+func WithoutCapabilities() Option {
+	return func(s *runtime.Spec) error {
+		if s.Process == nil {
+			s.Process = &runtime.Process{}
+		}
+		s.Process.Capabilities = &runtime.LinuxCapabilities{}
+		return nil
+	}
+}
+
 // WithHostNetwork configures the container to share the host's (i.e.
 // function-runtime-oci container's) network namespace.
 func WithHostNetwork() Option {
@@ -299,6 +364,214 @@ func WithHostNetwork() Option {
 	}
 }
 
+// WithEnv sets the supplied environment variables in the container, overriding
+// any that are already set.
+func WithEnv(env map[string]string) Option {
+	return func(s *runtime.Spec) error {
+		names := make([]string, 0, len(env))
+		for name := range env {
+			if name == "" || strings.Contains(name, "=") {
+				return errors.Errorf(errFmtInvalidEnvName, name)
+			}
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		if s.Process == nil {
+			s.Process = &runtime.Process{}
+		}
+
+		// Most programs use the first value of a variable that is set more
+		// than once, so we replace existing variables rather than appending.
+		filtered := make([]string, 0, len(s.Process.Env)+len(names))
+		for _, e := range s.Process.Env {
+			name, _, _ := strings.Cut(e, "=")
+			if _, ok := env[name]; ok {
+				continue
+			}
+			filtered = append(filtered, e)
+		}
+		for _, name := range names {
+			filtered = append(filtered, name+"="+env[name])
+		}
+		s.Process.Env = filtered
+		return nil
+	}
+}
+
+// WithReadOnlyBindMount bind mounts the supplied source path read-only at the
+// supplied destination path in the container.
+func WithReadOnlyBindMount(source, destination string) Option {
+	return func(s *runtime.Spec) error {
+		s.Mounts = append(s.Mounts, runtime.Mount{
+			Type:        "bind",
+			Destination: destination,
+			Source:      source,
+			Options:     []string{"rbind", "ro", "nosuid", "nodev", "noexec"},
+		})
+		return nil
+	}
+}
+
+// WithScratchMount bind mounts the supplied source path read-write at the
+// supplied destination path in the container, replacing any existing mount at
+// the destination path. It's intended for writable scratch space, e.g. /tmp.
+func WithScratchMount(source, destination string) Option {
+	return func(s *runtime.Spec) error {
+		m := runtime.Mount{
+			Type:        "bind",
+			Destination: destination,
+			Source:      source,
+			Options:     []string{"rbind", "rw", "nosuid", "nodev"},
+		}
+		for i := range s.Mounts {
+			if s.Mounts[i].Destination == destination {
+				s.Mounts[i] = m
+				return nil
+			}
+		}
+		s.Mounts = append(s.Mounts, m)
+		return nil
+	}
+}
+
+// WithoutMount removes any mount at the supplied destination path in the
+// container.
+func WithoutMount(destination string) Option {
+	return func(s *runtime.Spec) error {
+		filtered := make([]runtime.Mount, 0, len(s.Mounts))
+		for _, m := range s.Mounts {
+			if m.Destination != destination {
+				filtered = append(filtered, m)
+			}
+		}
+		s.Mounts = filtered
+		return nil
+	}
+}
+
+// WithWritableRootFS makes the container's rootfs writable.
+func WithWritableRootFS() Option {
+	return func(s *runtime.Spec) error {
+		if s.Root == nil {
+			s.Root = &runtime.Root{}
+		}
+		s.Root.Readonly = false
+		return nil
+	}
+}
+
+// WithFeatures removes any settings that an OCI runtime with the supplied
+// features doesn't support. It should be the last option applied. Nothing is
+// removed if the features are unknown (i.e. nil). It returns an error rather
+// than removing a namespace or seccomp profile, which would weaken the
+// container's isolation.
+func WithFeatures(f *features.Features) Option {
+	return func(s *runtime.Spec) error {
+		if f == nil || f.Linux == nil || s.Linux == nil {
+			return nil
+		}
+
+		if f.Linux.Namespaces != nil {
+			for _, ns := range s.Linux.Namespaces {
+				if !contains(f.Linux.Namespaces, string(ns.Type)) {
+					return errors.Errorf(errFmtNoNamespace, ns.Type)
+				}
+			}
+		}
+
+		if f.Linux.Capabilities != nil && s.Process != nil && s.Process.Capabilities != nil {
+			c := s.Process.Capabilities
+			c.Bounding = intersect(c.Bounding, f.Linux.Capabilities)
+			c.Effective = intersect(c.Effective, f.Linux.Capabilities)
+			c.Permitted = intersect(c.Permitted, f.Linux.Capabilities)
+			c.Inheritable = intersect(c.Inheritable, f.Linux.Capabilities)
+			c.Ambient = intersect(c.Ambient, f.Linux.Capabilities)
+		}
+
+		if sc := f.Linux.Seccomp; sc != nil && s.Linux.Seccomp != nil {
+			if err := withSeccompFeatures(s.Linux.Seccomp, sc); err != nil {
+				return errors.Wrap(err, errSeccompFeatures)
+			}
+		}
+
+		if a := f.Linux.Apparmor; a != nil && a.Enabled != nil && !*a.Enabled && s.Process != nil {
+			s.Process.ApparmorProfile = ""
+		}
+
+		if se := f.Linux.Selinux; se != nil && se.Enabled != nil && !*se.Enabled {
+			s.Linux.MountLabel = ""
+			if s.Process != nil {
+				s.Process.SelinuxLabel = ""
+			}
+		}
+
+		return nil
+	}
+}
+
+// withSeccompFeatures removes any architectures the OCI runtime doesn't
+// support from the supplied seccomp profile. It returns an error if the
+// runtime doesn't support seccomp, or doesn't support an action or operator
+// the profile uses. Unknown (i.e. nil) features are assumed to be supported.
+func withSeccompFeatures(sc *runtime.LinuxSeccomp, f *features.Seccomp) error {
+	if f.Enabled != nil && !*f.Enabled {
+		return errors.New(errNoSeccomp)
+	}
+	if f.Actions != nil {
+		if !contains(f.Actions, string(sc.DefaultAction)) {
+			return errors.Errorf(errFmtNoSeccompAction, sc.DefaultAction)
+		}
+		for _, sys := range sc.Syscalls {
+			if !contains(f.Actions, string(sys.Action)) {
+				return errors.Errorf(errFmtNoSeccompAction, sys.Action)
+			}
+		}
+	}
+	if f.Operators != nil {
+		for _, sys := range sc.Syscalls {
+			for _, a := range sys.Args {
+				if !contains(f.Operators, string(a.Op)) {
+					return errors.Errorf(errFmtNoSeccompOp, a.Op)
+				}
+			}
+		}
+	}
+	if f.Archs != nil && sc.Architectures != nil {
+		filtered := make([]runtime.Arch, 0, len(sc.Architectures))
+		for _, a := range sc.Architectures {
+			if contains(f.Archs, string(a)) {
+				filtered = append(filtered, a)
+			}
+		}
+		sc.Architectures = filtered
+	}
+	return nil
+}
+
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
+
+// intersect returns the elements of a that are also in b, preserving order.
+func intersect(a, b []string) []string {
+	if a == nil {
+		return nil
+	}
+	out := make([]string, 0, len(a))
+	for _, e := range a {
+		if contains(b, e) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
 // WithImageConfig extends a Spec with configuration derived from an OCI image
 // config file. If the image config specifies a user it will be resolved using
 // the supplied passwd and group files.
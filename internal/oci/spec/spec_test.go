@@ -27,6 +27,7 @@ import (
 	"github.com/google/go-cmp/cmp/cmpopts"
 	ociv1 "github.com/google/go-containerregistry/pkg/v1"
 	runtime "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/opencontainers/runtime-spec/specs-go/features"
 	"k8s.io/apimachinery/pkg/api/resource"
 
 	"github.com/crossplane/crossplane-runtime/pkg/errors"
@@ -238,6 +239,86 @@ func TestWithMemoryLimit(t *testing.T) {
 	}
 }
 
+func TestWithPidsLimit(t *testing.T) {
+	s := &runtime.Spec{}
+	if err := WithPidsLimit(64)(s); err != nil {
+		t.Errorf("WithPidsLimit(...): %v", err)
+	}
+	want := &runtime.Spec{Linux: &runtime.Linux{Resources: &runtime.LinuxResources{Pids: &runtime.LinuxPids{Limit: 64}}}}
+	if diff := cmp.Diff(want, s); diff != "" {
+		t.Errorf("WithPidsLimit(...): -want, +got:\n%s", diff)
+	}
+}
+
+func TestWithRlimit(t *testing.T) {
+	type args struct {
+		typ   string
+		limit uint64
+	}
+
+	cases := map[string]struct {
+		reason string
+		s      *runtime.Spec
+		args   args
+		want   *runtime.Spec
+	}{
+		"ReplaceRlimit": {
+			reason: "We should replace an existing rlimit of the same type.",
+			s: &runtime.Spec{
+				Process: &runtime.Process{Rlimits: []runtime.POSIXRlimit{{Type: RlimitOpenFiles, Soft: 1024, Hard: 1024}}},
+			},
+			args: args{typ: RlimitOpenFiles, limit: 64},
+			want: &runtime.Spec{
+				Process: &runtime.Process{Rlimits: []runtime.POSIXRlimit{{Type: RlimitOpenFiles, Soft: 64, Hard: 64}}},
+			},
+		},
+		"AppendRlimit": {
+			reason: "We should append an rlimit that isn't already set.",
+			s: &runtime.Spec{
+				Process: &runtime.Process{Rlimits: []runtime.POSIXRlimit{{Type: RlimitOpenFiles, Soft: 1024, Hard: 1024}}},
+			},
+			args: args{typ: RlimitCoreFileSize, limit: 0},
+			want: &runtime.Spec{
+				Process: &runtime.Process{Rlimits: []runtime.POSIXRlimit{
+					{Type: RlimitOpenFiles, Soft: 1024, Hard: 1024},
+					{Type: RlimitCoreFileSize, Soft: 0, Hard: 0},
+				}},
+			},
+		},
+		"EmptySpec": {
+			reason: "We should handle an empty spec without issue.",
+			s:      &runtime.Spec{},
+			args:   args{typ: RlimitProcesses, limit: 8},
+			want: &runtime.Spec{
+				Process: &runtime.Process{Rlimits: []runtime.POSIXRlimit{{Type: RlimitProcesses, Soft: 8, Hard: 8}}},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := WithRlimit(tc.args.typ, tc.args.limit)(tc.s)
+
+			if diff := cmp.Diff(tc.want, tc.s); diff != "" {
+				t.Errorf("\n%s\nWithRlimit(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if err != nil {
+				t.Errorf("\n%s\nWithRlimit(...): %v", tc.reason, err)
+			}
+		})
+	}
+}
+
+func TestWithoutCapabilities(t *testing.T) {
+	s, _ := New()
+	if err := WithoutCapabilities()(s); err != nil {
+		t.Errorf("WithoutCapabilities(...): %v", err)
+	}
+	if diff := cmp.Diff(&runtime.LinuxCapabilities{}, s.Process.Capabilities); diff != "" {
+		t.Errorf("WithoutCapabilities(...): -want, +got:\n%s", diff)
+	}
+}
+
 func TestWithHostNetwork(t *testing.T) {
 	type want struct {
 		s   *runtime.Spec
@@ -305,6 +386,418 @@ func TestWithHostNetwork(t *testing.T) {
 	}
 }
 
+func TestWithEnv(t *testing.T) {
+	type args struct {
+		env map[string]string
+	}
+	type want struct {
+		s   *runtime.Spec
+		err error
+	}
+
+	cases := map[string]struct {
+		reason string
+		s      *runtime.Spec
+		args   args
+		want   want
+	}{
+		"OverrideEnv": {
+			reason: "We should override existing variables and append new ones, sorted by name.",
+			s: &runtime.Spec{
+				Process: &runtime.Process{Env: []string{"PATH=/bin", "GREETING=hi"}},
+			},
+			args: args{
+				env: map[string]string{"TOKEN": "secret", "GREETING": "hello"},
+			},
+			want: want{
+				s: &runtime.Spec{
+					Process: &runtime.Process{Env: []string{"PATH=/bin", "GREETING=hello", "TOKEN=secret"}},
+				},
+			},
+		},
+		"EmptySpec": {
+			reason: "We should handle an empty spec without issue.",
+			s:      &runtime.Spec{},
+			args: args{
+				env: map[string]string{"TOKEN": "secret"},
+			},
+			want: want{
+				s: &runtime.Spec{
+					Process: &runtime.Process{Env: []string{"TOKEN=secret"}},
+				},
+			},
+		},
+		"InvalidName": {
+			reason: "We should return an error if a variable name contains '='.",
+			s:      &runtime.Spec{},
+			args: args{
+				env: map[string]string{"TO=KEN": "secret"},
+			},
+			want: want{
+				s:   &runtime.Spec{},
+				err: errors.Errorf(errFmtInvalidEnvName, "TO=KEN"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := WithEnv(tc.args.env)(tc.s)
+
+			if diff := cmp.Diff(tc.want.s, tc.s, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("\n%s\nWithEnv(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nWithEnv(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestWithReadOnlyBindMount(t *testing.T) {
+	s := &runtime.Spec{}
+	err := WithReadOnlyBindMount("/tmp/secrets", "/run/secrets")(s)
+
+	want := &runtime.Spec{
+		Mounts: []runtime.Mount{{
+			Type:        "bind",
+			Destination: "/run/secrets",
+			Source:      "/tmp/secrets",
+			Options:     []string{"rbind", "ro", "nosuid", "nodev", "noexec"},
+		}},
+	}
+	if diff := cmp.Diff(want, s); diff != "" {
+		t.Errorf("WithReadOnlyBindMount(...): -want, +got:\n%s", diff)
+	}
+	if err != nil {
+		t.Errorf("WithReadOnlyBindMount(...): %v", err)
+	}
+}
+
+func TestWithScratchMount(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		s      *runtime.Spec
+		want   *runtime.Spec
+	}{
+		"NewMount": {
+			reason: "We should add a mount if there's none at the destination.",
+			s:      &runtime.Spec{},
+			want: &runtime.Spec{
+				Mounts: []runtime.Mount{{
+					Type:        "bind",
+					Destination: "/tmp",
+					Source:      "/scratch",
+					Options:     []string{"rbind", "rw", "nosuid", "nodev"},
+				}},
+			},
+		},
+		"ReplaceMount": {
+			reason: "We should replace any existing mount at the destination.",
+			s: &runtime.Spec{
+				Mounts: []runtime.Mount{
+					{Type: "bind", Destination: "/proc", Source: "/proc"},
+					{Type: "tmpfs", Destination: "/tmp", Source: "tmp"},
+				},
+			},
+			want: &runtime.Spec{
+				Mounts: []runtime.Mount{
+					{Type: "bind", Destination: "/proc", Source: "/proc"},
+					{
+						Type:        "bind",
+						Destination: "/tmp",
+						Source:      "/scratch",
+						Options:     []string{"rbind", "rw", "nosuid", "nodev"},
+					},
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := WithScratchMount("/scratch", "/tmp")(tc.s)
+			if diff := cmp.Diff(tc.want, tc.s); diff != "" {
+				t.Errorf("\n%s\nWithScratchMount(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if err != nil {
+				t.Errorf("\n%s\nWithScratchMount(...): %v", tc.reason, err)
+			}
+		})
+	}
+}
+
+func TestWithoutMount(t *testing.T) {
+	s := &runtime.Spec{
+		Mounts: []runtime.Mount{
+			{Type: "bind", Destination: "/proc", Source: "/proc"},
+			{Type: "tmpfs", Destination: "/tmp", Source: "tmp"},
+		},
+	}
+	want := &runtime.Spec{
+		Mounts: []runtime.Mount{
+			{Type: "bind", Destination: "/proc", Source: "/proc"},
+		},
+	}
+
+	err := WithoutMount("/tmp")(s)
+	if diff := cmp.Diff(want, s); diff != "" {
+		t.Errorf("WithoutMount(...): -want, +got:\n%s", diff)
+	}
+	if err != nil {
+		t.Errorf("WithoutMount(...): %v", err)
+	}
+}
+
+func TestWithFeatures(t *testing.T) {
+	disabled := false
+
+	type args struct {
+		f *features.Features
+		s *runtime.Spec
+	}
+	type want struct {
+		s   *runtime.Spec
+		err error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"UnknownFeatures": {
+			reason: "We shouldn't remove anything if we don't know what the runtime supports.",
+			args: args{
+				s: &runtime.Spec{
+					Linux: &runtime.Linux{Namespaces: []runtime.LinuxNamespace{{Type: runtime.CgroupNamespace}}},
+				},
+			},
+			want: want{
+				s: &runtime.Spec{
+					Linux: &runtime.Linux{Namespaces: []runtime.LinuxNamespace{{Type: runtime.CgroupNamespace}}},
+				},
+			},
+		},
+		"RemoveUnsupportedCapabilities": {
+			reason: "We should remove capabilities if the runtime doesn't support them.",
+			args: args{
+				f: &features.Features{
+					Linux: &features.Linux{
+						Namespaces:   []string{"pid", "mount"},
+						Capabilities: []string{"CAP_KILL"},
+					},
+				},
+				s: &runtime.Spec{
+					Process: &runtime.Process{
+						Capabilities: &runtime.LinuxCapabilities{
+							Bounding:  []string{"CAP_AUDIT_WRITE", "CAP_KILL"},
+							Effective: []string{"CAP_KILL", "CAP_NET_BIND_SERVICE"},
+						},
+					},
+					Linux: &runtime.Linux{
+						Namespaces: []runtime.LinuxNamespace{
+							{Type: runtime.PIDNamespace},
+							{Type: runtime.MountNamespace},
+						},
+					},
+				},
+			},
+			want: want{
+				s: &runtime.Spec{
+					Process: &runtime.Process{
+						Capabilities: &runtime.LinuxCapabilities{
+							Bounding:  []string{"CAP_KILL"},
+							Effective: []string{"CAP_KILL"},
+						},
+					},
+					Linux: &runtime.Linux{
+						Namespaces: []runtime.LinuxNamespace{
+							{Type: runtime.PIDNamespace},
+							{Type: runtime.MountNamespace},
+						},
+					},
+				},
+			},
+		},
+		"UnsupportedNamespace": {
+			reason: "We should return an error rather than remove a namespace the runtime doesn't support.",
+			args: args{
+				f: &features.Features{
+					Linux: &features.Linux{Namespaces: []string{"pid", "mount"}},
+				},
+				s: &runtime.Spec{
+					Linux: &runtime.Linux{
+						Namespaces: []runtime.LinuxNamespace{
+							{Type: runtime.PIDNamespace},
+							{Type: runtime.CgroupNamespace},
+						},
+					},
+				},
+			},
+			want: want{
+				s: &runtime.Spec{
+					Linux: &runtime.Linux{
+						Namespaces: []runtime.LinuxNamespace{
+							{Type: runtime.PIDNamespace},
+							{Type: runtime.CgroupNamespace},
+						},
+					},
+				},
+				err: errors.Errorf(errFmtNoNamespace, runtime.CgroupNamespace),
+			},
+		},
+		"SeccompDisabled": {
+			reason: "We should return an error rather than remove the seccomp profile if the runtime doesn't support seccomp.",
+			args: args{
+				f: &features.Features{
+					Linux: &features.Linux{
+						Seccomp: &features.Seccomp{Enabled: &disabled},
+					},
+				},
+				s: &runtime.Spec{
+					Linux: &runtime.Linux{
+						Seccomp: &runtime.LinuxSeccomp{DefaultAction: runtime.ActErrno},
+					},
+				},
+			},
+			want: want{
+				s: &runtime.Spec{
+					Linux: &runtime.Linux{
+						Seccomp: &runtime.LinuxSeccomp{DefaultAction: runtime.ActErrno},
+					},
+				},
+				err: errors.Wrap(errors.New(errNoSeccomp), errSeccompFeatures),
+			},
+		},
+		"UnconfinedSeccompDisabled": {
+			reason: "We shouldn't return an error if the runtime doesn't support seccomp and there's no seccomp profile.",
+			args: args{
+				f: &features.Features{
+					Linux: &features.Linux{
+						Seccomp: &features.Seccomp{Enabled: &disabled},
+					},
+				},
+				s: &runtime.Spec{
+					Linux: &runtime.Linux{},
+				},
+			},
+			want: want{
+				s: &runtime.Spec{
+					Linux: &runtime.Linux{},
+				},
+			},
+		},
+		"UnsupportedSeccompAction": {
+			reason: "We should return an error if the runtime doesn't support a seccomp action the profile uses.",
+			args: args{
+				f: &features.Features{
+					Linux: &features.Linux{
+						Seccomp: &features.Seccomp{Actions: []string{string(runtime.ActErrno)}},
+					},
+				},
+				s: &runtime.Spec{
+					Linux: &runtime.Linux{
+						Seccomp: &runtime.LinuxSeccomp{
+							DefaultAction: runtime.ActErrno,
+							Syscalls:      []runtime.LinuxSyscall{{Names: []string{"read"}, Action: runtime.ActAllow}},
+						},
+					},
+				},
+			},
+			want: want{
+				s: &runtime.Spec{
+					Linux: &runtime.Linux{
+						Seccomp: &runtime.LinuxSeccomp{
+							DefaultAction: runtime.ActErrno,
+							Syscalls:      []runtime.LinuxSyscall{{Names: []string{"read"}, Action: runtime.ActAllow}},
+						},
+					},
+				},
+				err: errors.Wrap(errors.Errorf(errFmtNoSeccompAction, runtime.ActAllow), errSeccompFeatures),
+			},
+		},
+		"UnsupportedSeccompOperator": {
+			reason: "We should return an error if the runtime doesn't support a seccomp operator the profile uses.",
+			args: args{
+				f: &features.Features{
+					Linux: &features.Linux{
+						Seccomp: &features.Seccomp{Operators: []string{string(runtime.OpEqualTo)}},
+					},
+				},
+				s: &runtime.Spec{
+					Linux: &runtime.Linux{
+						Seccomp: &runtime.LinuxSeccomp{
+							DefaultAction: runtime.ActErrno,
+							Syscalls: []runtime.LinuxSyscall{{
+								Names:  []string{"clone"},
+								Action: runtime.ActAllow,
+								Args:   []runtime.LinuxSeccompArg{{Index: 0, Value: 1, Op: runtime.OpMaskedEqual}},
+							}},
+						},
+					},
+				},
+			},
+			want: want{
+				s: &runtime.Spec{
+					Linux: &runtime.Linux{
+						Seccomp: &runtime.LinuxSeccomp{
+							DefaultAction: runtime.ActErrno,
+							Syscalls: []runtime.LinuxSyscall{{
+								Names:  []string{"clone"},
+								Action: runtime.ActAllow,
+								Args:   []runtime.LinuxSeccompArg{{Index: 0, Value: 1, Op: runtime.OpMaskedEqual}},
+							}},
+						},
+					},
+				},
+				err: errors.Wrap(errors.Errorf(errFmtNoSeccompOp, runtime.OpMaskedEqual), errSeccompFeatures),
+			},
+		},
+		"UnsupportedSeccompArchitecture": {
+			reason: "We should remove any architectures the runtime doesn't support from the seccomp profile.",
+			args: args{
+				f: &features.Features{
+					Linux: &features.Linux{
+						Seccomp: &features.Seccomp{Archs: []string{string(runtime.ArchX86_64)}},
+					},
+				},
+				s: &runtime.Spec{
+					Linux: &runtime.Linux{
+						Seccomp: &runtime.LinuxSeccomp{
+							DefaultAction: runtime.ActErrno,
+							Architectures: []runtime.Arch{runtime.ArchX86_64, runtime.ArchX32},
+						},
+					},
+				},
+			},
+			want: want{
+				s: &runtime.Spec{
+					Linux: &runtime.Linux{
+						Seccomp: &runtime.LinuxSeccomp{
+							DefaultAction: runtime.ActErrno,
+							Architectures: []runtime.Arch{runtime.ArchX86_64},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := WithFeatures(tc.args.f)(tc.args.s)
+
+			if diff := cmp.Diff(tc.want.s, tc.args.s); diff != "" {
+				t.Errorf("\n%s\nWithFeatures(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nWithFeatures(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
 func TestWithImageConfig(t *testing.T) {
 	type args struct {
 		cfg    *ociv1.ConfigFile
@@ -25,6 +25,8 @@ import (
 	"path/filepath"
 
 	ociv1 "github.com/google/go-containerregistry/pkg/v1"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/crossplane/crossplane-runtime/pkg/errors"
 	"github.com/crossplane/crossplane-runtime/pkg/resource"
@@ -32,6 +34,7 @@ import (
 	"github.com/crossplane/function-runtime-oci/internal/oci/layer"
 	"github.com/crossplane/function-runtime-oci/internal/oci/spec"
 	"github.com/crossplane/function-runtime-oci/internal/oci/store"
+	"github.com/crossplane/function-runtime-oci/internal/tracing"
 )
 
 // Error strings
@@ -143,10 +146,25 @@ type CachingBundler struct {
 	spec   RuntimeSpecWriter
 }
 
+// A CachingBundlerOption configures a CachingBundler.
+type CachingBundlerOption func(c *CachingBundler)
+
+// WithUpperLimit limits the size of the tmpfs that stores the upper layer of
+// each container's rootfs overlay to the supplied number of bytes. This bounds
+// how much a container with a writable rootfs can write to it. Zero means the
+// tmpfs default, which is half of the host's memory.
+func WithUpperLimit(bytes int64) CachingBundlerOption {
+	return func(c *CachingBundler) {
+		c.bundle = BundleBootstrapperFn(func(path string, parentLayerPaths []string) (Bundle, error) {
+			return bootstrapBundle(path, parentLayerPaths, bytes)
+		})
+	}
+}
+
 // NewCachingBundler returns a bundler that creates container filesystems as
 // overlays on their image's layers, which are stored as extracted, overlay
 // compatible directories of files.
-func NewCachingBundler(root string) (*CachingBundler, error) {
+func NewCachingBundler(root string, o ...CachingBundlerOption) (*CachingBundler, error) {
 	l, err := NewCachingLayerResolver(filepath.Join(root, store.DirOverlays))
 	if err != nil {
 		return nil, errors.Wrap(err, errMkLayerStore)
@@ -158,6 +176,9 @@ func NewCachingBundler(root string) (*CachingBundler, error) {
 		bundle: BundleBootstrapperFn(BootstrapBundle),
 		spec:   RuntimeSpecWriterFn(spec.Write),
 	}
+	for _, fn := range o {
+		fn(s)
+	}
 	return s, nil
 }
 
@@ -237,8 +258,15 @@ func (s *CachingLayerResolver) Resolve(ctx context.Context, l ociv1.Layer, paren
 		return "", errors.Wrap(err, errGetDigest)
 	}
 
+	ctx, span := tracing.Tracer().Start(ctx, "resolve-layer", trace.WithAttributes(attribute.String("layer", d.String())))
+	path, err := s.resolve(ctx, d, l, parents...)
+	tracing.End(span, err)
+	return path, err
+}
+
+func (s *CachingLayerResolver) resolve(ctx context.Context, d ociv1.Hash, l ociv1.Layer, parents ...ociv1.Layer) (string, error) {
 	path := filepath.Join(s.root, d.Algorithm, d.Hex)
-	if _, err = os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
 		// The path exists or we encountered an error other than ErrNotExist.
 		// Either way return the path and the wrapped error - errors.Wrap will
 		// return nil if the path exists.
@@ -296,30 +324,37 @@ type Bundle struct {
 
 // BootstrapBundle creates and returns an OCI runtime bundle with a root
 // filesystem backed by a temporary (tmpfs) overlay atop the supplied lower
-// layer paths.
+// layer paths. The bundle itself is a tmpfs, so its runtime spec - which may
+// include sensitive environment variables - is never written to disk.
 func BootstrapBundle(path string, parentLayerPaths []string) (Bundle, error) {
+	return bootstrapBundle(path, parentLayerPaths, 0)
+}
+
+// bootstrapBundle bootstraps a bundle whose tmpfs is limited to the supplied
+// size in bytes. Zero means the tmpfs default.
+func bootstrapBundle(path string, parentLayerPaths []string, size int64) (Bundle, error) {
 	if err := os.MkdirAll(path, 0700); err != nil {
 		return Bundle{}, errors.Wrap(err, "cannot create bundle dir")
 	}
 
-	if err := os.Mkdir(filepath.Join(path, overlayDirTmpfs), 0700); err != nil {
-		_ = os.RemoveAll(path)
-		return Bundle{}, errors.Wrap(err, errMkOverlayDirTmpfs)
-	}
-
-	tm := TmpFSMount{Mountpoint: filepath.Join(path, overlayDirTmpfs)}
+	tm := store.TmpFSMount{Mountpoint: path, Size: size}
 	if err := tm.Mount(); err != nil {
 		_ = os.RemoveAll(path)
 		return Bundle{}, errors.Wrap(err, "cannot mount workdir tmpfs")
 	}
+	cleanup := func() {
+		_ = tm.Unmount()
+		_ = os.RemoveAll(path)
+	}
 
 	for _, p := range []string{
+		filepath.Join(path, overlayDirTmpfs),
 		filepath.Join(path, overlayDirTmpfs, overlayDirUpper),
 		filepath.Join(path, overlayDirTmpfs, overlayDirWork),
 		filepath.Join(path, store.DirRootFS),
 	} {
 		if err := os.Mkdir(p, 0700); err != nil {
-			_ = os.RemoveAll(path)
+			cleanup()
 			return Bundle{}, errors.Wrapf(err, "cannot create %s dir", p)
 		}
 	}
@@ -331,7 +366,7 @@ func BootstrapBundle(path string, parentLayerPaths []string) (Bundle, error) {
 		Mountpoint: filepath.Join(path, store.DirRootFS),
 	}
 	if err := om.Mount(); err != nil {
-		_ = os.RemoveAll(path)
+		cleanup()
 		return Bundle{}, errors.Wrap(err, "cannot mount workdir overlayfs")
 	}
 
@@ -342,6 +377,10 @@ func BootstrapBundle(path string, parentLayerPaths []string) (Bundle, error) {
 // Path to the OCI bundle.
 func (b Bundle) Path() string { return b.path }
 
+// ScratchPath returns the path of the tmpfs that stores any writes to the
+// bundle's root filesystem. The tmpfs also stores the bundle's runtime spec.
+func (b Bundle) ScratchPath() string { return filepath.Join(b.path, overlayDirTmpfs) }
+
 // Cleanup the OCI bundle.
 func (b Bundle) Cleanup() error {
 	for _, m := range b.mounts {
@@ -358,13 +397,8 @@ type Mount interface {
 	Unmount() error
 }
 
-// A TmpFSMount represents a mount of type tmpfs.
-type TmpFSMount struct {
-	Mountpoint string
-}
-
 // An OverlayMount represents a mount of type overlay.
-type OverlayMount struct { //nolint:revive // overlay.OverlayMount makes sense given that overlay.TmpFSMount exists too.
+type OverlayMount struct { //nolint:revive // overlay.OverlayMount makes sense given that store.TmpFSMount exists too.
 	Mountpoint string
 	Lower      []string
 	Upper      string
@@ -33,18 +33,6 @@ import (
 // compiles and passes tests during development. Avoid adding code to this file
 // unless it actually needs Linux to run.
 
-// Mount the tmpfs mount.
-func (m TmpFSMount) Mount() error {
-	var flags uintptr
-	return errors.Wrapf(unix.Mount("tmpfs", m.Mountpoint, "tmpfs", flags, ""), "cannot mount tmpfs at %q", m.Mountpoint)
-}
-
-// Unmount the tmpfs mount.
-func (m TmpFSMount) Unmount() error {
-	var flags int
-	return errors.Wrapf(unix.Unmount(m.Mountpoint, flags), "cannot unmount tmpfs at %q", m.Mountpoint)
-}
-
 // Mount the overlay mount.
 func (m OverlayMount) Mount() error {
 	var flags uintptr
@@ -24,12 +24,6 @@ import (
 
 const errLinuxOnly = "overlayfs is only only supported on Linux"
 
-// Mount returns an error on non-Linux systems.
-func (m TmpFSMount) Mount() error { return errors.New(errLinuxOnly) }
-
-// Unmount returns an error on non-Linux systems.
-func (m TmpFSMount) Unmount() error { return errors.New(errLinuxOnly) }
-
 // Mount returns an error on non-Linux systems.
 func (m OverlayMount) Mount() error { return errors.New(errLinuxOnly) }
 
@@ -248,7 +248,6 @@ func TestResolve(t *testing.T) {
 		wdopts  []NewLayerWorkdirOption
 	}
 	type args struct {
-		ctx     context.Context
 		l       ociv1.Layer
 		parents []ociv1.Layer
 	}
@@ -440,7 +439,7 @@ func TestResolve(t *testing.T) {
 				wantPath = filepath.Join(tmp, tc.want.path)
 			}
 
-			path, err := c.Resolve(tc.args.ctx, tc.args.l, tc.args.parents...)
+			path, err := c.Resolve(context.Background(), tc.args.l, tc.args.parents...)
 
 			if diff := cmp.Diff(wantPath, path); diff != "" {
 				t.Errorf("\n%s\nResolve(...): -want, +got:\n%s", tc.reason, diff)
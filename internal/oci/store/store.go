@@ -46,6 +46,7 @@ const (
 	DirImages     = "i"
 	DirOverlays   = "o"
 	DirContainers = "c"
+	DirVerified   = "v"
 )
 
 // Bundle paths.
@@ -58,6 +59,9 @@ const (
 // Error strings
 const (
 	errMkDigestStore    = "cannot make digest store"
+	errMkVerifiedStore  = "cannot make verified image store"
+	errStatVerified     = "cannot determine whether image was verified"
+	errStoreVerified    = "cannot store image verification"
 	errReadDigest       = "cannot read digest"
 	errParseDigest      = "cannot parse digest"
 	errStoreDigest      = "cannot store digest"
@@ -101,9 +105,19 @@ type Bundle interface {
 	Cleanup() error
 }
 
-// A Digest store is used to map OCI references to digests. Each mapping is a
-// file. The filename is the SHA256 hash of the reference, and the content is
-// the digest in algo:hex format.
+// A TmpFSMount represents a mount of type tmpfs.
+type TmpFSMount struct {
+	Mountpoint string
+
+	// Size limits the tmpfs to the supplied number of bytes. Zero means the
+	// tmpfs default, which is half of the host's memory.
+	Size int64
+}
+
+// A Digest store is used to map OCI references to digests. A reference may
+// resolve to a different digest on each platform, for example if it's to an
+// image index. Each mapping is a file. The filename is the SHA256 hash of the
+// reference and platform, and the content is the digest in algo:hex format.
 type Digest struct{ root string }
 
 // NewDigest returns a store used to map OCI references to digests.
@@ -115,9 +129,16 @@ func NewDigest(root string) (*Digest, error) {
 	return &Digest{root: path}, errors.Wrap(err, errMkDigestStore)
 }
 
-// Hash returns the stored hash for the supplied reference.
-func (d *Digest) Hash(r name.Reference) (ociv1.Hash, error) {
-	b, err := os.ReadFile(d.path(r))
+// Hash returns the stored hash for the supplied reference and platform.
+func (d *Digest) Hash(r name.Reference, p ociv1.Platform) (ociv1.Hash, error) {
+	b, err := os.ReadFile(d.path(r, p))
+
+	// Caches written before mappings were keyed by platform map the reference
+	// alone to the digest of the image we resolved for go-containerregistry's
+	// default platform, linux/amd64. They're a cache miss for any other.
+	if errors.Is(err, os.ErrNotExist) && p.OS == "linux" && p.Architecture == "amd64" && p.Variant == "" {
+		b, err = os.ReadFile(d.path(r, ociv1.Platform{}))
+	}
 	if err != nil {
 		return ociv1.Hash{}, errors.Wrap(err, errReadDigest)
 	}
@@ -125,13 +146,51 @@ func (d *Digest) Hash(r name.Reference) (ociv1.Hash, error) {
 	return h, errors.Wrap(err, errParseDigest)
 }
 
-// WriteHash maps the supplied reference to the supplied hash.
-func (d *Digest) WriteHash(r name.Reference, h ociv1.Hash) error {
-	return errors.Wrap(os.WriteFile(d.path(r), []byte(h.String()), 0600), errStoreDigest)
+// WriteHash maps the supplied reference and platform to the supplied hash.
+func (d *Digest) WriteHash(r name.Reference, p ociv1.Platform, h ociv1.Hash) error {
+	return errors.Wrap(os.WriteFile(d.path(r, p), []byte(h.String()), 0600), errStoreDigest)
+}
+
+func (d *Digest) path(r name.Reference, p ociv1.Platform) string {
+	key := r.String()
+
+	// Mappings for an unspecified platform are keyed by the reference alone.
+	if p.OS != "" || p.Architecture != "" {
+		key = key + " " + p.String()
+	}
+	return filepath.Join(d.root, fmt.Sprintf("%x", sha256.Sum256([]byte(key))))
+}
+
+// A Verified store records which images were verified by which sets of keys.
+// Each record is an empty file. The filename is the SHA256 hash of the image
+// digest and the fingerprint of the set of keys.
+type Verified struct{ root string }
+
+// NewVerified returns a store used to record which images were verified.
+func NewVerified(root string) (*Verified, error) {
+	path := filepath.Join(root, DirVerified, "sha256")
+	err := os.MkdirAll(path, 0700)
+	return &Verified{root: path}, errors.Wrap(err, errMkVerifiedStore)
+}
+
+// Verified returns true if the supplied hash was verified by the set of keys
+// with the supplied fingerprint.
+func (v *Verified) Verified(h ociv1.Hash, keys string) (bool, error) {
+	_, err := os.Stat(v.path(h, keys))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, errors.Wrap(err, errStatVerified)
+}
+
+// WriteVerified records that the supplied hash was verified by the set of keys
+// with the supplied fingerprint.
+func (v *Verified) WriteVerified(h ociv1.Hash, keys string) error {
+	return errors.Wrap(os.WriteFile(v.path(h, keys), nil, 0600), errStoreVerified)
 }
 
-func (d *Digest) path(r name.Reference) string {
-	return filepath.Join(d.root, fmt.Sprintf("%x", sha256.Sum256([]byte(r.String()))))
+func (v *Verified) path(h ociv1.Hash, keys string) string {
+	return filepath.Join(v.root, fmt.Sprintf("%x", sha256.Sum256([]byte(h.String()+"@"+keys))))
 }
 
 // An Image store is used to store OCI images and their layers. It uses a
@@ -0,0 +1,43 @@
+//go:build linux
+
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+)
+
+// Mount the tmpfs mount.
+func (m TmpFSMount) Mount() error {
+	var flags uintptr
+	var data string
+	if m.Size > 0 {
+		data = fmt.Sprintf("size=%d", m.Size)
+	}
+	return errors.Wrapf(unix.Mount("tmpfs", m.Mountpoint, "tmpfs", flags, data), "cannot mount tmpfs at %q", m.Mountpoint)
+}
+
+// Unmount the tmpfs mount.
+func (m TmpFSMount) Unmount() error {
+	var flags int
+	return errors.Wrapf(unix.Unmount(m.Mountpoint, flags), "cannot unmount tmpfs at %q", m.Mountpoint)
+}
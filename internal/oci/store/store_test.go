@@ -58,6 +58,7 @@ func (l *MockLayer) Uncompressed() (io.ReadCloser, error) { return l.MockUncompr
 func TestHash(t *testing.T) {
 	type args struct {
 		r name.Reference
+		p ociv1.Platform
 	}
 	type want struct {
 		h   ociv1.Hash
@@ -109,6 +110,67 @@ func TestHash(t *testing.T) {
 				err: nil,
 			},
 		},
+		"SuccessfulPlatformRead": {
+			reason: "We should return the stored hash for the supplied platform.",
+			files: map[string][]byte{
+				"276640b463239572f62edd97253f05e0de082e9888f57dac0b83d2149efa59e0": []byte("sha256:0000000000000000000000000000000000000000000000000000000000000000"),
+				"02228e9398f038860b40e89e533f1e7c825f493b7887ccc239a25081d491020b": []byte("sha256:c34045c1a1db8d1b3fca8a692198466952daae07eaf6104b4c87ed3b55b6af1b"),
+			},
+			args: args{
+				r: name.MustParseReference("example.org/image"),
+				p: ociv1.Platform{OS: "linux", Architecture: "arm64"},
+			},
+			want: want{
+				h: ociv1.Hash{
+					Algorithm: "sha256",
+					Hex:       "c34045c1a1db8d1b3fca8a692198466952daae07eaf6104b4c87ed3b55b6af1b",
+				},
+				err: nil,
+			},
+		},
+		"SuccessfulLegacyRead": {
+			reason: "We should return the hash stored for the reference alone if no hash is stored for linux/amd64, because the cache was written before hashes were stored by platform and go-containerregistry resolved linux/amd64 by default.",
+			files: map[string][]byte{
+				"276640b463239572f62edd97253f05e0de082e9888f57dac0b83d2149efa59e0": []byte("sha256:c34045c1a1db8d1b3fca8a692198466952daae07eaf6104b4c87ed3b55b6af1b"),
+			},
+			args: args{
+				r: name.MustParseReference("example.org/image"),
+				p: ociv1.Platform{OS: "linux", Architecture: "amd64"},
+			},
+			want: want{
+				h: ociv1.Hash{
+					Algorithm: "sha256",
+					Hex:       "c34045c1a1db8d1b3fca8a692198466952daae07eaf6104b4c87ed3b55b6af1b",
+				},
+				err: nil,
+			},
+		},
+		"LegacyOtherPlatform": {
+			reason: "We should not return the hash stored for the reference alone if no hash is stored for a platform other than linux/amd64, because it's the digest of the linux/amd64 image.",
+			files: map[string][]byte{
+				"276640b463239572f62edd97253f05e0de082e9888f57dac0b83d2149efa59e0": []byte("sha256:c34045c1a1db8d1b3fca8a692198466952daae07eaf6104b4c87ed3b55b6af1b"),
+			},
+			args: args{
+				r: name.MustParseReference("example.org/image"),
+				p: ociv1.Platform{OS: "linux", Architecture: "arm64"},
+			},
+			want: want{
+				err: os.ErrNotExist,
+			},
+		},
+		"OtherPlatform": {
+			reason: "We should not return a hash stored for a different platform.",
+			files: map[string][]byte{
+				"b0daaf48452f1bf2bf9206245726f9c93ae7fdae36fe33ea83f3cf642dbd0122": []byte("sha256:c34045c1a1db8d1b3fca8a692198466952daae07eaf6104b4c87ed3b55b6af1b"),
+			},
+			args: args{
+				r: name.MustParseReference("example.org/image"),
+				p: ociv1.Platform{OS: "linux", Architecture: "arm64"},
+			},
+			want: want{
+				err: os.ErrNotExist,
+			},
+		},
 	}
 
 	for name, tc := range cases {
@@ -132,7 +194,7 @@ func TestHash(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			h, err := c.Hash(tc.args.r)
+			h, err := c.Hash(tc.args.r, tc.args.p)
 			if diff := cmp.Diff(tc.want.h, h); diff != "" {
 				t.Errorf("\n%s\nHash(...): -want, +got:\n%s", tc.reason, diff)
 			}
@@ -351,3 +413,46 @@ func TestWriteLayer(t *testing.T) {
 		})
 	}
 }
+
+func TestVerified(t *testing.T) {
+	tmp, err := os.MkdirTemp(os.TempDir(), t.Name())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.RemoveAll(tmp)
+
+	v, err := NewVerified(tmp)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	h := ociv1.Hash{Algorithm: "sha256", Hex: strings.Repeat("a", 64)}
+
+	got, err := v.Verified(h, "keys")
+	if diff := cmp.Diff(false, got); diff != "" {
+		t.Errorf("Verified(...): -want, +got:\n%s", diff)
+	}
+	if err != nil {
+		t.Errorf("Verified(...): %v", err)
+	}
+
+	if err := v.WriteVerified(h, "keys"); err != nil {
+		t.Fatalf("WriteVerified(...): %v", err)
+	}
+
+	got, err = v.Verified(h, "keys")
+	if diff := cmp.Diff(true, got); diff != "" {
+		t.Errorf("Verified(...): -want verified by the same keys, +got:\n%s", diff)
+	}
+	if err != nil {
+		t.Errorf("Verified(...): %v", err)
+	}
+
+	got, err = v.Verified(h, "other-keys")
+	if diff := cmp.Diff(false, got); diff != "" {
+		t.Errorf("Verified(...): -want not verified by other keys, +got:\n%s", diff)
+	}
+	if err != nil {
+		t.Errorf("Verified(...): %v", err)
+	}
+}
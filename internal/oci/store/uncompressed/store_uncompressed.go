@@ -41,9 +41,19 @@ const (
 	errApplyLayer       = "cannot extract layer tarball"
 	errCloseLayer       = "cannot close layer tarball"
 	errWriteRuntimeSpec = "cannot write OCI runtime spec"
+	errMkSpecDir        = "cannot make runtime spec directory"
+	errMountSpecDir     = "cannot mount runtime spec tmpfs"
+	errUnmountSpecDir   = "cannot unmount runtime spec tmpfs"
 	errCleanupBundle    = "cannot cleanup OCI runtime bundle"
 )
 
+// The directory within each container's directory that is its OCI runtime
+// bundle, i.e. that contains its runtime spec.
+const dirBundle = "bundle"
+
+// The size of the tmpfs that stores each bundle's runtime spec.
+const specTmpFSBytes = 1 << 20
+
 // A TarballApplicator applies (i.e. extracts) an OCI layer tarball.
 // https://github.com/opencontainers/image-spec/blob/v1.0/layer.md
 type TarballApplicator interface {
@@ -65,6 +75,15 @@ type RuntimeSpecWriterFn func(path string, o ...spec.Option) error
 // Write an OCI runtime spec to the supplied path.
 func (fn RuntimeSpecWriterFn) Write(path string, o ...spec.Option) error { return fn(path, o...) }
 
+// A Mount of a filesystem.
+type Mount interface {
+	Mount() error
+	Unmount() error
+}
+
+// A TmpFSFn returns a tmpfs mount at the supplied path.
+type TmpFSFn func(path string) Mount
+
 // A Bundler prepares OCI runtime bundles for use by an OCI runtime. It creates
 // the bundle's rootfs by extracting the supplied image's uncompressed layer
 // tarballs.
@@ -72,6 +91,7 @@ type Bundler struct {
 	root    string
 	tarball TarballApplicator
 	spec    RuntimeSpecWriter
+	tmpfs   TmpFSFn
 }
 
 // NewBundler returns a an OCI runtime bundler that creates a bundle's rootfs by
@@ -81,11 +101,16 @@ func NewBundler(root string) *Bundler {
 		root:    filepath.Join(root, store.DirContainers),
 		tarball: layer.NewStackingExtractor(layer.NewWhiteoutHandler(layer.NewExtractHandler())),
 		spec:    RuntimeSpecWriterFn(spec.Write),
+		tmpfs: func(path string) Mount {
+			return store.TmpFSMount{Mountpoint: path, Size: specTmpFSBytes}
+		},
 	}
 	return s
 }
 
-// Bundle returns an OCI bundle ready for use by an OCI runtime.
+// Bundle returns an OCI bundle ready for use by an OCI runtime. The bundle's
+// rootfs is extracted to disk, but its runtime spec - which may include
+// sensitive environment variables - is written to a tmpfs.
 func (c *Bundler) Bundle(ctx context.Context, i ociv1.Image, id string, o ...spec.Option) (store.Bundle, error) {
 	cfg, err := i.ConfigFile()
 	if err != nil {
@@ -102,7 +127,7 @@ func (c *Bundler) Bundle(ctx context.Context, i ociv1.Image, id string, o ...spe
 	if err := os.MkdirAll(rootfs, 0700); err != nil {
 		return nil, errors.Wrap(err, errMkRootFS)
 	}
-	b := Bundle{path: path}
+	b := Bundle{root: path}
 
 	if err := store.Validate(i); err != nil {
 		return nil, err
@@ -125,12 +150,25 @@ func (c *Bundler) Bundle(ctx context.Context, i ociv1.Image, id string, o ...spe
 		}
 	}
 
+	b.path = filepath.Join(path, dirBundle)
+	if err := os.Mkdir(b.path, 0700); err != nil {
+		_ = b.Cleanup()
+		return nil, errors.Wrap(err, errMkSpecDir)
+	}
+	m := c.tmpfs(b.path)
+	if err := m.Mount(); err != nil {
+		_ = b.Cleanup()
+		return nil, errors.Wrap(err, errMountSpecDir)
+	}
+	b.tmpfs = m
+
 	// Inject config derived from the image first, so that any options passed in
-	// by the caller will override it.
+	// by the caller will override it. The rootfs isn't within the bundle, so
+	// its path must be absolute.
 	p, g := filepath.Join(rootfs, "etc", "passwd"), filepath.Join(rootfs, "etc", "group")
-	opts := append([]spec.Option{spec.WithImageConfig(cfg, p, g), spec.WithRootFS(store.DirRootFS, true)}, o...)
+	opts := append([]spec.Option{spec.WithImageConfig(cfg, p, g), spec.WithRootFS(rootfs, true)}, o...)
 
-	if err = c.spec.Write(filepath.Join(path, store.FileSpec), opts...); err != nil {
+	if err = c.spec.Write(filepath.Join(b.path, store.FileSpec), opts...); err != nil {
 		_ = b.Cleanup()
 		return nil, errors.Wrap(err, errWriteRuntimeSpec)
 	}
@@ -141,7 +179,9 @@ func (c *Bundler) Bundle(ctx context.Context, i ociv1.Image, id string, o ...spe
 // An Bundle is an OCI runtime bundle. Its root filesystem is a temporary
 // extraction of its image's cached layers.
 type Bundle struct {
-	path string
+	root  string
+	path  string
+	tmpfs Mount
 }
 
 // Path to the OCI bundle.
@@ -149,5 +189,10 @@ func (b Bundle) Path() string { return b.path }
 
 // Cleanup the OCI bundle.
 func (b Bundle) Cleanup() error {
-	return errors.Wrap(os.RemoveAll(b.path), errCleanupBundle)
+	if b.tmpfs != nil {
+		if err := b.tmpfs.Unmount(); err != nil {
+			return errors.Wrap(err, errUnmountSpecDir)
+		}
+	}
+	return errors.Wrap(os.RemoveAll(b.root), errCleanupBundle)
 }
@@ -64,6 +64,11 @@ type MockRuntimeSpecWriter struct{ err error }
 
 func (c *MockRuntimeSpecWriter) Write(_ string, _ ...spec.Option) error { return c.err }
 
+type MockMount struct{ err error }
+
+func (m *MockMount) Mount() error   { return m.err }
+func (m *MockMount) Unmount() error { return nil }
+
 type MockCloser struct {
 	io.Reader
 
@@ -78,6 +83,7 @@ func TestBundle(t *testing.T) {
 	type params struct {
 		tarball TarballApplicator
 		spec    RuntimeSpecWriter
+		tmpfs   Mount
 	}
 	type args struct {
 		ctx context.Context
@@ -176,11 +182,32 @@ func TestBundle(t *testing.T) {
 				err: errors.Wrap(errBoom, errCloseLayer),
 			},
 		},
+		"MountSpecDirError": {
+			reason: "We should return any error encountered mounting the tmpfs for the bundle's OCI runtime spec.",
+			params: params{
+				tarball: &MockTarballApplicator{},
+				tmpfs:   &MockMount{err: errBoom},
+			},
+			args: args{
+				i: &MockImage{
+					MockConfigFile: func() (*ociv1.ConfigFile, error) { return &ociv1.ConfigFile{}, nil },
+					MockLayers: func() ([]ociv1.Layer, error) {
+						return []ociv1.Layer{&MockLayer{
+							MockUncompressed: func() (io.ReadCloser, error) { return io.NopCloser(strings.NewReader("")), nil },
+						}}, nil
+					},
+				},
+			},
+			want: want{
+				err: errors.Wrap(errBoom, errMountSpecDir),
+			},
+		},
 		"WriteRuntimeSpecError": {
 			reason: "We should return any error encountered creating the bundle's OCI runtime spec.",
 			params: params{
 				tarball: &MockTarballApplicator{},
 				spec:    &MockRuntimeSpecWriter{err: errBoom},
+				tmpfs:   &MockMount{},
 			},
 			args: args{
 				i: &MockImage{
@@ -201,6 +228,7 @@ func TestBundle(t *testing.T) {
 			params: params{
 				tarball: &MockTarballApplicator{},
 				spec:    &MockRuntimeSpecWriter{},
+				tmpfs:   &MockMount{},
 			},
 			args: args{
 				i: &MockImage{
@@ -232,6 +260,7 @@ func TestBundle(t *testing.T) {
 				root:    tmp,
 				tarball: tc.params.tarball,
 				spec:    tc.params.spec,
+				tmpfs:   func(_ string) Mount { return tc.params.tmpfs },
 			}
 
 			got, err := c.Bundle(tc.args.ctx, tc.args.i, tc.args.id, tc.args.o...)
@@ -0,0 +1,400 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	ociv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+)
+
+// Error strings.
+const (
+	errReadPublicKeys       = "cannot read public keys"
+	errParsePublicKey       = "cannot parse public key"
+	errNoPublicKeys         = "no public keys found"
+	errMarshalPublicKey     = "cannot marshal public key"
+	errFetchSignatures      = "cannot fetch image signatures"
+	errFetchReferrers       = "cannot fetch image referrers"
+	errFetchSignatureImage  = "cannot fetch image signature"
+	errReadSignatureLayers  = "cannot read image signature layers"
+	errReadSignaturePayload = "cannot read image signature payload"
+	errLoadVerification     = "cannot load cached image verification"
+	errStoreVerification    = "cannot cache image verification"
+	errVerifyNever          = "cannot fetch image signatures with image pull policy " + string(ImagePullPolicyNever)
+	errNotSigned            = "image is not signed"
+	errNoTrustedSignature   = "image is not signed by a trusted key"
+
+	errFmtUnsupportedKey = "unsupported public key type %T"
+)
+
+// Cosign signature formats.
+// https://github.com/sigstore/cosign/blob/main/specs/SIGNATURE_SPEC.md
+const (
+	// AnnotationCosignSignature is the annotation of a signature layer that
+	// contains the base64 encoded signature of the layer's payload.
+	AnnotationCosignSignature = "dev.cosignproject.cosign/signature"
+
+	// ArtifactTypeCosignSignature is the artifact type of a cosign signature
+	// discovered using the OCI referrers API.
+	ArtifactTypeCosignSignature = "application/vnd.dev.cosign.artifact.sig.v1+json"
+
+	// SignatureTagSuffix is the suffix of the tag at which cosign stores the
+	// signatures of an image, e.g. sha256-<hex>.sig.
+	SignatureTagSuffix = ".sig"
+)
+
+// A Signature of an OCI image.
+type Signature struct {
+	// Payload that was signed. For cosign signatures it's a 'simple signing'
+	// JSON document that identifies the signed image by digest.
+	Payload []byte
+
+	// Signature of the payload.
+	Signature []byte
+}
+
+// A SignatureClient fetches the signatures of OCI images.
+type SignatureClient interface {
+	// Signatures returns any signatures of the supplied image digest.
+	Signatures(ctx context.Context, d name.Digest, o ...ImageClientOption) ([]Signature, error)
+}
+
+// A VerificationCache caches whether images were verified by a set of keys.
+type VerificationCache interface {
+	// Verified returns true if the supplied image hash was verified by the
+	// set of keys with the supplied fingerprint.
+	Verified(h ociv1.Hash, keys string) (bool, error)
+
+	// WriteVerified records that the supplied image hash was verified by the
+	// set of keys with the supplied fingerprint.
+	WriteVerified(h ociv1.Hash, keys string) error
+}
+
+// A Verifier verifies OCI images.
+type Verifier interface {
+	// Verify the image with the supplied hashes, which was pulled using the
+	// supplied reference. The first hash is the image's digest. Any others
+	// are digests the reference resolved to on its way to the image, e.g. the
+	// digest of a multi-platform image index. A signature of any of them
+	// verifies the image.
+	Verify(ctx context.Context, r name.Reference, hs []ociv1.Hash, o ...ImageClientOption) error
+}
+
+// A verificationError indicates that an image failed verification.
+type verificationError struct{ error }
+
+func (e verificationError) Unwrap() error { return e.error }
+
+// IsVerificationFailed returns true if the supplied error indicates that an
+// image failed verification, e.g. because it wasn't signed by a trusted key.
+func IsVerificationFailed(err error) bool {
+	return errors.As(err, &verificationError{})
+}
+
+// A SignatureVerifier verifies that OCI images have a cosign-style signature
+// made by one of a set of trusted public keys. Results are cached per image
+// digest.
+type SignatureVerifier struct {
+	keys        []crypto.PublicKey
+	fingerprint string
+	remote      SignatureClient
+	cache       VerificationCache
+}
+
+// NewSignatureVerifier returns a Verifier that requires images to be signed
+// by one of the supplied public keys.
+func NewSignatureVerifier(keys []crypto.PublicKey, c VerificationCache, r SignatureClient) (*SignatureVerifier, error) {
+	if len(keys) == 0 {
+		return nil, errors.New(errNoPublicKeys)
+	}
+	fp, err := fingerprint(keys)
+	if err != nil {
+		return nil, err
+	}
+	return &SignatureVerifier{keys: keys, fingerprint: fp, remote: r, cache: c}, nil
+}
+
+// Verify that the image with the supplied hashes has a signature made by one
+// of the trusted public keys. The first hash is the image's digest. Any others
+// are digests the reference resolved to on its way to the image, e.g. the
+// digest of a multi-platform image index; a signature of the index verifies
+// the images it lists. The signatures are fetched from the repository of the
+// supplied reference, unless the image was already verified by the same set of
+// keys.
+func (v *SignatureVerifier) Verify(ctx context.Context, r name.Reference, hs []ociv1.Hash, o ...ImageClientOption) error {
+	if len(hs) == 0 {
+		return verificationError{errors.New(errNotSigned)}
+	}
+
+	ok, err := v.cache.Verified(hs[0], v.fingerprint)
+	if err != nil {
+		return errors.Wrap(err, errLoadVerification)
+	}
+	if ok {
+		return nil
+	}
+
+	if parse(o...).pull == ImagePullPolicyNever {
+		return verificationError{errors.New(errVerifyNever)}
+	}
+
+	signed := false
+	for _, h := range hs {
+		sigs, err := v.remote.Signatures(ctx, r.Context().Digest(h.String()), o...)
+		if err != nil {
+			return errors.Wrap(err, errFetchSignatures)
+		}
+		signed = signed || len(sigs) > 0
+
+		for _, s := range sigs {
+			if !v.trusted(s) || !signs(s.Payload, h) {
+				continue
+			}
+			// We record that the image was verified, even if it was its
+			// index that was signed. It's the image we cache and run.
+			return errors.Wrap(v.cache.WriteVerified(hs[0], v.fingerprint), errStoreVerification)
+		}
+	}
+	if !signed {
+		return verificationError{errors.New(errNotSigned)}
+	}
+	return verificationError{errors.New(errNoTrustedSignature)}
+}
+
+// trusted returns true if the supplied signature was made by a trusted key.
+func (v *SignatureVerifier) trusted(s Signature) bool {
+	for _, k := range v.keys {
+		if verifySignature(k, s.Payload, s.Signature) {
+			return true
+		}
+	}
+	return false
+}
+
+// A simpleSigning payload, as signed by cosign. We only care about the digest
+// of the signed image.
+type simpleSigning struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+	} `json:"critical"`
+}
+
+// signs returns true if the supplied simple signing payload identifies the
+// image with the supplied hash. Otherwise a signature of one image could be
+// passed off as a signature of another.
+func signs(payload []byte, h ociv1.Hash) bool {
+	ss := &simpleSigning{}
+	if err := json.Unmarshal(payload, ss); err != nil {
+		return false
+	}
+	return ss.Critical.Image.DockerManifestDigest == h.String()
+}
+
+// verifySignature returns true if the supplied signature of the supplied
+// payload was made by the supplied public key. ECDSA and RSA signatures are of
+// the SHA256 digest of the payload, per cosign.
+func verifySignature(k crypto.PublicKey, payload, sig []byte) bool {
+	d := sha256.Sum256(payload)
+	switch pk := k.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(pk, d[:], sig)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(pk, crypto.SHA256, d[:], sig) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(pk, payload, sig)
+	}
+	return false
+}
+
+// fingerprint returns a fingerprint of the supplied set of keys that doesn't
+// depend on their order.
+func fingerprint(keys []crypto.PublicKey) (string, error) {
+	ders := make([]string, len(keys))
+	for i, k := range keys {
+		der, err := x509.MarshalPKIXPublicKey(k)
+		if err != nil {
+			return "", errors.Wrap(err, errMarshalPublicKey)
+		}
+		ders[i] = string(der)
+	}
+	sort.Strings(ders)
+	h := sha256.New()
+	for _, der := range ders {
+		d := sha256.Sum256([]byte(der))
+		_, _ = h.Write(d[:])
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ParsePublicKeysFromPath parses a file containing one or more PEM encoded
+// PKIX public keys, e.g. as generated by cosign generate-key-pair.
+func ParsePublicKeysFromPath(path string) ([]crypto.PublicKey, error) {
+	b, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, errors.Wrap(err, errReadPublicKeys)
+	}
+	return ParsePublicKeys(b)
+}
+
+// ParsePublicKeys parses one or more PEM encoded PKIX public keys. ECDSA, RSA,
+// and Ed25519 keys are supported.
+func ParsePublicKeys(data []byte) ([]crypto.PublicKey, error) {
+	keys := make([]crypto.PublicKey, 0)
+	for {
+		var b *pem.Block
+		b, data = pem.Decode(data)
+		if b == nil {
+			break
+		}
+		k, err := x509.ParsePKIXPublicKey(b.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, errParsePublicKey)
+		}
+		switch k.(type) {
+		case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+		default:
+			return nil, errors.Errorf(errFmtUnsupportedKey, k)
+		}
+		keys = append(keys, k)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New(errNoPublicKeys)
+	}
+	return keys, nil
+}
+
+// Signatures fetches the cosign signatures of the supplied image digest. It
+// finds signatures using both the OCI referrers API and cosign's tag naming
+// convention. Registries that support neither simply return no signatures.
+func (i *RemoteClient) Signatures(ctx context.Context, d name.Digest, o ...ImageClientOption) ([]Signature, error) {
+	opts := parse(o...)
+	rOpts := []remote.Option{remote.WithContext(ctx)}
+	if opts.auth != nil {
+		rOpts = append(rOpts, remote.WithAuth(opts.auth))
+	}
+	if opts.transport != nil {
+		rOpts = append(rOpts, remote.WithTransport(opts.transport))
+	}
+
+	sigImages := make([]ociv1.Image, 0)
+
+	// Registries that don't support the referrers API return a fallback index
+	// of images tagged per the referrers tag schema, which may be empty.
+	idx, err := remote.Referrers(d, append(rOpts, remote.WithFilter("artifactType", ArtifactTypeCosignSignature))...)
+	if err != nil && !IsNotFound(err) {
+		return nil, errors.Wrap(err, errFetchReferrers)
+	}
+	if idx != nil {
+		m, err := idx.IndexManifest()
+		if err != nil {
+			return nil, errors.Wrap(err, errFetchReferrers)
+		}
+		for _, desc := range m.Manifests {
+			if desc.ArtifactType != ArtifactTypeCosignSignature {
+				continue
+			}
+			img, err := remote.Image(d.Context().Digest(desc.Digest.String()), rOpts...)
+			if err != nil {
+				return nil, errors.Wrap(err, errFetchSignatureImage)
+			}
+			sigImages = append(sigImages, img)
+		}
+	}
+
+	h, err := ociv1.NewHash(d.DigestStr())
+	if err != nil {
+		return nil, errors.Wrap(err, errFetchSignatureImage)
+	}
+	img, err := remote.Image(d.Context().Tag(h.Algorithm+"-"+h.Hex+SignatureTagSuffix), rOpts...)
+	if err != nil && !IsNotFound(err) {
+		return nil, errors.Wrap(err, errFetchSignatureImage)
+	}
+	if img != nil {
+		sigImages = append(sigImages, img)
+	}
+
+	sigs := make([]Signature, 0)
+	for _, img := range sigImages {
+		s, err := signatures(img)
+		if err != nil {
+			return nil, err
+		}
+		sigs = append(sigs, s...)
+	}
+	return sigs, nil
+}
+
+// signatures returns the cosign signatures stored in the supplied image. Each
+// layer is a payload, and its annotation is the signature of the payload.
+func signatures(img ociv1.Image) ([]Signature, error) {
+	m, err := img.Manifest()
+	if err != nil {
+		return nil, errors.Wrap(err, errReadSignatureLayers)
+	}
+	out := make([]Signature, 0, len(m.Layers))
+	for _, desc := range m.Layers {
+		b64, ok := desc.Annotations[AnnotationCosignSignature]
+		if !ok {
+			continue
+		}
+		sig, err := base64.StdEncoding.DecodeString(b64)
+		if err != nil {
+			// A malformed signature can't verify anything.
+			continue
+		}
+		l, err := img.LayerByDigest(desc.Digest)
+		if err != nil {
+			return nil, errors.Wrap(err, errReadSignatureLayers)
+		}
+		rc, err := l.Compressed()
+		if err != nil {
+			return nil, errors.Wrap(err, errReadSignaturePayload)
+		}
+		payload, err := io.ReadAll(io.LimitReader(rc, maxSignaturePayloadBytes))
+		_ = rc.Close()
+		if err != nil {
+			return nil, errors.Wrap(err, errReadSignaturePayload)
+		}
+		out = append(out, Signature{Payload: payload, Signature: sig})
+	}
+	return out, nil
+}
+
+// Simple signing payloads are small JSON documents. We don't want to read an
+// arbitrarily large layer into memory.
+const maxSignaturePayloadBytes = 1 << 20 // 1 MiB
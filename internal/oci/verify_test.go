@@ -0,0 +1,522 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	ociv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+type MockSignatureClient struct {
+	MockSignatures func(ctx context.Context, d name.Digest, o ...ImageClientOption) ([]Signature, error)
+}
+
+func (c *MockSignatureClient) Signatures(ctx context.Context, d name.Digest, o ...ImageClientOption) ([]Signature, error) {
+	return c.MockSignatures(ctx, d, o...)
+}
+
+type MockVerificationCache struct {
+	MockVerified      func(h ociv1.Hash, keys string) (bool, error)
+	MockWriteVerified func(h ociv1.Hash, keys string) error
+}
+
+func (c *MockVerificationCache) Verified(h ociv1.Hash, keys string) (bool, error) {
+	return c.MockVerified(h, keys)
+}
+
+func (c *MockVerificationCache) WriteVerified(h ociv1.Hash, keys string) error {
+	return c.MockWriteVerified(h, keys)
+}
+
+func payload(h ociv1.Hash) []byte {
+	return []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"example.org/fn"},"image":{"docker-manifest-digest":%q},"type":"cosign container image signature"},"optional":null}`, h.String()))
+}
+
+func sign(t *testing.T, k *ecdsa.PrivateKey, payload []byte) []byte {
+	t.Helper()
+	d := sha256.Sum256(payload)
+	sig, err := ecdsa.SignASN1(rand.Reader, k, d[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sig
+}
+
+func TestSignatureVerifierVerify(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	trusted, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	untrusted, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := ociv1.Hash{Algorithm: "sha256", Hex: strings.Repeat("a", 64)}
+	other := ociv1.Hash{Algorithm: "sha256", Hex: strings.Repeat("b", 64)}
+	idx := ociv1.Hash{Algorithm: "sha256", Hex: strings.Repeat("c", 64)}
+	ref := name.MustParseReference("example.org/fn:v1")
+
+	notVerified := func(_ ociv1.Hash, _ string) (bool, error) { return false, nil }
+	wrote := func(_ ociv1.Hash, _ string) error { return nil }
+
+	type args struct {
+		hs []ociv1.Hash
+		o  []ImageClientOption
+	}
+
+	cases := map[string]struct {
+		reason         string
+		remote         SignatureClient
+		cache          VerificationCache
+		args           args
+		want           error
+		wantRejection  bool
+		wantCacheWrite bool
+	}{
+		"CachedVerification": {
+			reason: "We shouldn't fetch signatures of an image that was already verified by the same keys.",
+			cache: &MockVerificationCache{
+				MockVerified: func(_ ociv1.Hash, _ string) (bool, error) { return true, nil },
+			},
+		},
+		"CacheError": {
+			reason: "We should return an error if we can't read the verification cache.",
+			cache: &MockVerificationCache{
+				MockVerified: func(_ ociv1.Hash, _ string) (bool, error) { return false, errBoom },
+			},
+			want: errors.Wrap(errBoom, errLoadVerification),
+		},
+		"NeverPull": {
+			reason: "We should reject an image we can't verify without pulling its signatures.",
+			cache:  &MockVerificationCache{MockVerified: notVerified},
+			args: args{
+				o: []ImageClientOption{WithPullPolicy(ImagePullPolicyNever)},
+			},
+			want:          verificationError{errors.New(errVerifyNever)},
+			wantRejection: true,
+		},
+		"FetchSignaturesError": {
+			reason: "We should return an error if we can't fetch signatures.",
+			cache:  &MockVerificationCache{MockVerified: notVerified},
+			remote: &MockSignatureClient{
+				MockSignatures: func(_ context.Context, _ name.Digest, _ ...ImageClientOption) ([]Signature, error) {
+					return nil, errBoom
+				},
+			},
+			want: errors.Wrap(errBoom, errFetchSignatures),
+		},
+		"NotSigned": {
+			reason: "We should reject an image with no signatures.",
+			cache:  &MockVerificationCache{MockVerified: notVerified},
+			remote: &MockSignatureClient{
+				MockSignatures: func(_ context.Context, _ name.Digest, _ ...ImageClientOption) ([]Signature, error) {
+					return nil, nil
+				},
+			},
+			want:          verificationError{errors.New(errNotSigned)},
+			wantRejection: true,
+		},
+		"UntrustedKey": {
+			reason: "We should reject an image that isn't signed by a trusted key.",
+			cache:  &MockVerificationCache{MockVerified: notVerified},
+			remote: &MockSignatureClient{
+				MockSignatures: func(_ context.Context, _ name.Digest, _ ...ImageClientOption) ([]Signature, error) {
+					return []Signature{{Payload: payload(h), Signature: sign(t, untrusted, payload(h))}}, nil
+				},
+			},
+			want:          verificationError{errors.New(errNoTrustedSignature)},
+			wantRejection: true,
+		},
+		"OtherImage": {
+			reason: "We should reject a trusted signature of a different image.",
+			cache:  &MockVerificationCache{MockVerified: notVerified},
+			remote: &MockSignatureClient{
+				MockSignatures: func(_ context.Context, _ name.Digest, _ ...ImageClientOption) ([]Signature, error) {
+					return []Signature{{Payload: payload(other), Signature: sign(t, trusted, payload(other))}}, nil
+				},
+			},
+			want:          verificationError{errors.New(errNoTrustedSignature)},
+			wantRejection: true,
+		},
+		"VerifiedIndex": {
+			reason: "We should accept, and cache, an image whose index is signed by a trusted key.",
+			cache: &MockVerificationCache{
+				MockVerified: notVerified,
+				MockWriteVerified: func(got ociv1.Hash, _ string) error {
+					if got != h {
+						return errors.Errorf("wrote verification of %s, not the image", got)
+					}
+					return nil
+				},
+			},
+			remote: &MockSignatureClient{
+				MockSignatures: func(_ context.Context, d name.Digest, _ ...ImageClientOption) ([]Signature, error) {
+					if d.DigestStr() != idx.String() {
+						return nil, nil
+					}
+					return []Signature{{Payload: payload(idx), Signature: sign(t, trusted, payload(idx))}}, nil
+				},
+			},
+			args: args{
+				hs: []ociv1.Hash{h, idx},
+			},
+			wantCacheWrite: true,
+		},
+		"IndexOfOtherImage": {
+			reason: "We should reject an image whose index's trusted signature is of a different index.",
+			cache:  &MockVerificationCache{MockVerified: notVerified},
+			remote: &MockSignatureClient{
+				MockSignatures: func(_ context.Context, d name.Digest, _ ...ImageClientOption) ([]Signature, error) {
+					if d.DigestStr() != idx.String() {
+						return nil, nil
+					}
+					return []Signature{{Payload: payload(other), Signature: sign(t, trusted, payload(other))}}, nil
+				},
+			},
+			args: args{
+				hs: []ociv1.Hash{h, idx},
+			},
+			want:          verificationError{errors.New(errNoTrustedSignature)},
+			wantRejection: true,
+		},
+		"Verified": {
+			reason: "We should accept, and cache, an image signed by a trusted key.",
+			cache:  &MockVerificationCache{MockVerified: notVerified, MockWriteVerified: wrote},
+			remote: &MockSignatureClient{
+				MockSignatures: func(_ context.Context, d name.Digest, _ ...ImageClientOption) ([]Signature, error) {
+					if d.DigestStr() != h.String() {
+						return nil, errBoom
+					}
+					return []Signature{
+						{Payload: payload(h), Signature: sign(t, untrusted, payload(h))},
+						{Payload: payload(h), Signature: sign(t, trusted, payload(h))},
+					}, nil
+				},
+			},
+			wantCacheWrite: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			written := false
+			if c, ok := tc.cache.(*MockVerificationCache); ok && c.MockWriteVerified != nil {
+				fn := c.MockWriteVerified
+				c.MockWriteVerified = func(h ociv1.Hash, keys string) error {
+					written = true
+					return fn(h, keys)
+				}
+			}
+
+			v, err := NewSignatureVerifier([]crypto.PublicKey{trusted.Public()}, tc.cache, tc.remote)
+			if err != nil {
+				t.Fatal(err)
+			}
+			hs := tc.args.hs
+			if hs == nil {
+				hs = []ociv1.Hash{h}
+			}
+			err = v.Verify(context.Background(), ref, hs, tc.args.o...)
+			if diff := cmp.Diff(tc.want, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nVerify(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.wantRejection, IsVerificationFailed(err)); diff != "" {
+				t.Errorf("\n%s\nIsVerificationFailed(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.wantCacheWrite, written); diff != "" {
+				t.Errorf("\n%s\nVerify(...): -want cache write, +got cache write:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRemoteClientSignatures(t *testing.T) {
+	srv := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := name.ParseReference(host + "/fn:v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.Write(ref, img); err != nil {
+		t.Fatal(err)
+	}
+	h, err := img.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, sig := pushSignature(t, k, ref.Context(), h)
+
+	got, err := (&RemoteClient{}).Signatures(context.Background(), ref.Context().Digest(h.String()))
+	if err != nil {
+		t.Fatalf("Signatures(...): %v", err)
+	}
+	want := []Signature{{Payload: p, Signature: sig}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Signatures(...): -want, +got:\n%s", diff)
+	}
+
+	// An image with no signatures has none, rather than an error.
+	unsigned, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uh, err := unsigned.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = (&RemoteClient{}).Signatures(context.Background(), ref.Context().Digest(uh.String()))
+	if err != nil {
+		t.Fatalf("Signatures(...): %v", err)
+	}
+	if diff := cmp.Diff([]Signature{}, got); diff != "" {
+		t.Errorf("Signatures(...): -want no signatures, +got:\n%s", diff)
+	}
+}
+
+// pushSignature pushes a cosign signature of the supplied digest to the
+// supplied repository using the tag convention. It returns the signed payload
+// and its signature.
+func pushSignature(t *testing.T, k *ecdsa.PrivateKey, repo name.Repository, h ociv1.Hash) ([]byte, []byte) {
+	t.Helper()
+	p := payload(h)
+	sig := sign(t, k, p)
+	l := static.NewLayer(p, types.MediaType("application/vnd.dev.cosign.simplesigning.v1+json"))
+	sigImg, err := mutate.Append(empty.Image, mutate.Addendum{
+		Layer:       l,
+		Annotations: map[string]string{AnnotationCosignSignature: base64.StdEncoding.EncodeToString(sig)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.Write(repo.Tag(h.Algorithm+"-"+h.Hex+SignatureTagSuffix), sigImg); err != nil {
+		t.Fatal(err)
+	}
+	return p, sig
+}
+
+func TestVerifySignedIndex(t *testing.T) {
+	srv := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	amd64, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	arm64, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx := mutate.AppendManifests(empty.Index,
+		mutate.IndexAddendum{Add: amd64, Descriptor: ociv1.Descriptor{Platform: &ociv1.Platform{OS: "linux", Architecture: "amd64"}}},
+		mutate.IndexAddendum{Add: arm64, Descriptor: ociv1.Descriptor{Platform: &ociv1.Platform{OS: "linux", Architecture: "arm64"}}},
+	)
+	signed, err := name.ParseReference(host + "/fn:signed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.WriteIndex(signed, idx); err != nil {
+		t.Fatal(err)
+	}
+	unsigned, err := name.ParseReference(host + "/fn:unsigned")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.WriteIndex(unsigned, mutate.Annotations(idx, map[string]string{"unsigned": "true"}).(ociv1.ImageIndex)); err != nil {
+		t.Fatal(err)
+	}
+
+	// cosign signs the digest of an index, not of each of its images.
+	ih, err := idx.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pushSignature(t, k, signed.Context(), ih)
+
+	want, err := arm64.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing is cached, so every image is pulled and verified.
+	c := &MockVerificationCache{
+		MockVerified:      func(_ ociv1.Hash, _ string) (bool, error) { return false, nil },
+		MockWriteVerified: func(_ ociv1.Hash, _ string) error { return nil },
+	}
+	v, err := NewSignatureVerifier([]crypto.PublicKey{k.Public()}, c, &RemoteClient{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := map[ociv1.Hash]ociv1.Image{}
+	p := NewCachingPuller(
+		&MockHashCache{MockWriteHash: func(_ name.Reference, _ ociv1.Platform, _ ociv1.Hash) error { return nil }},
+		&MockImageCache{
+			MockImage: func(h ociv1.Hash) (ociv1.Image, error) { return local[h], nil },
+			MockWriteImage: func(img ociv1.Image) error {
+				h, err := img.Digest()
+				local[h] = img
+				return err
+			},
+		},
+		&RemoteClient{},
+		WithVerifier(v),
+	)
+
+	cases := map[string]struct {
+		reason        string
+		ref           name.Reference
+		wantRejection bool
+	}{
+		"SignedIndexByTag": {
+			reason: "We should accept an image resolved by tag from an index signed by a trusted key.",
+			ref:    signed,
+		},
+		"SignedIndexByDigest": {
+			reason: "We should accept an image resolved by digest from an index signed by a trusted key.",
+			ref:    signed.Context().Digest(ih.String()),
+		},
+		"UnsignedIndex": {
+			reason:        "We should reject an image resolved from an index that isn't signed.",
+			ref:           unsigned,
+			wantRejection: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			img, err := p.Image(context.Background(), tc.ref, WithPullPolicy(ImagePullPolicyAlways), WithPlatform(ociv1.Platform{OS: "linux", Architecture: "arm64"}))
+			if diff := cmp.Diff(tc.wantRejection, IsVerificationFailed(err)); diff != "" {
+				t.Fatalf("\n%s\nImage(...): -want rejection, +got rejection:\n%s\nerror: %v", tc.reason, diff, err)
+			}
+			if tc.wantRejection {
+				return
+			}
+			if err != nil {
+				t.Fatalf("\n%s\nImage(...): %v", tc.reason, err)
+			}
+			got, err := img.Digest()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("\n%s\nImage(...): -want digest, +got digest:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestParsePublicKeys(t *testing.T) {
+	ek, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ed, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encode := func(k crypto.PublicKey) []byte {
+		der, err := x509.MarshalPKIXPublicKey(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	}
+
+	type want struct {
+		keys []crypto.PublicKey
+		err  error
+	}
+
+	cases := map[string]struct {
+		reason string
+		data   []byte
+		want   want
+	}{
+		"MultipleKeys": {
+			reason: "We should parse every key in the data.",
+			data:   bytes.Join([][]byte{encode(ek.Public()), encode(ed)}, nil),
+			want:   want{keys: []crypto.PublicKey{ek.Public(), ed}},
+		},
+		"NoKeys": {
+			reason: "We should return an error if the data contains no keys.",
+			data:   []byte("not a key"),
+			want:   want{err: errors.New(errNoPublicKeys)},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			keys, err := ParsePublicKeys(tc.data)
+			if diff := cmp.Diff(tc.want.keys, keys); diff != "" {
+				t.Errorf("\n%s\nParsePublicKeys(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nParsePublicKeys(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tracing supports OpenTelemetry tracing of Composition Function runs.
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+)
+
+// Error strings.
+const (
+	errNewExporter = "cannot create OTLP trace exporter"
+)
+
+const (
+	tracerName  = "github.com/crossplane/function-runtime-oci"
+	serviceName = "function-runtime-oci"
+)
+
+// W3C trace context is passed to spark using these environment variables, which
+// correspond to the traceparent and tracestate HTTP headers.
+const (
+	envTraceParent = "TRACEPARENT"
+	envTraceState  = "TRACESTATE"
+)
+
+var propagator = propagation.TraceContext{}
+
+// Tracer returns the function runtime's tracer. Spans are discarded unless a
+// TracerProvider has been installed using Setup.
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// Setup installs a global TracerProvider that exports spans to the supplied
+// OTLP gRPC endpoint. The returned function flushes any buffered spans and
+// shuts the TracerProvider down.
+func Setup(ctx context.Context, endpoint string, insecure bool) (func(context.Context) error, error) {
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(endpoint)}
+	if insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	exp, err := otlptracegrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, errNewExporter)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagator)
+	return tp.Shutdown, nil
+}
+
+// End the supplied span, recording the supplied error if it's not nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// UnaryServerInterceptor returns a gRPC interceptor that starts a span for each
+// call. The span is a child of any W3C trace context found in the incoming
+// gRPC metadata.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		ctx = propagator.Extract(ctx, metadataCarrier(md))
+
+		ctx, span := Tracer().Start(ctx, info.FullMethod, trace.WithSpanKind(trace.SpanKindServer))
+		rsp, err := handler(ctx, req)
+		End(span, err)
+		return rsp, err
+	}
+}
+
+// Environ returns environment variables that carry the W3C trace context of
+// the supplied context to a child process.
+func Environ(ctx context.Context) []string {
+	c := propagation.MapCarrier{}
+	propagator.Inject(ctx, c)
+
+	env := make([]string, 0, len(c))
+	for k, v := range c {
+		env = append(env, strings.ToUpper(k)+"="+v)
+	}
+	return env
+}
+
+// FromEnviron returns a copy of the supplied context with any W3C trace
+// context found in the supplied environment variables, as produced by Environ.
+func FromEnviron(ctx context.Context, env []string) context.Context {
+	c := propagation.MapCarrier{}
+	for _, kv := range env {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case envTraceParent, envTraceState:
+			c.Set(strings.ToLower(k), v)
+		}
+	}
+	return propagator.Extract(ctx, c)
+}
+
+// metadataCarrier adapts gRPC metadata to satisfy propagation.TextMapCarrier.
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	v := metadata.MD(c).Get(key)
+	if len(v) == 0 {
+		return ""
+	}
+	return v[0]
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	out := make([]string, 0, len(c))
+	for k := range c {
+		out = append(out, k)
+	}
+	return out
+}
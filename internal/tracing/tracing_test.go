@@ -0,0 +1,60 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const traceParent = "00-0af7651916cd43dd8448eb211c80319c-b7ad49a7a8db8ae2-01"
+
+func TestEnvironRoundTrip(t *testing.T) {
+	want := FromEnviron(context.Background(), []string{"PATH=/bin", envTraceParent + "=" + traceParent})
+	if !trace.SpanContextFromContext(want).IsValid() {
+		t.Fatalf("FromEnviron(...): want valid span context")
+	}
+
+	got := FromEnviron(context.Background(), Environ(want))
+	if diff := cmp.Diff(trace.SpanContextFromContext(want).TraceID(), trace.SpanContextFromContext(got).TraceID()); diff != "" {
+		t.Errorf("FromEnviron(Environ(...)): -want trace ID, +got trace ID:\n%s", diff)
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("traceparent", traceParent))
+
+	var got trace.SpanContext
+	handler := func(ctx context.Context, _ any) (any, error) {
+		got = trace.SpanContextFromContext(ctx)
+		return nil, nil
+	}
+	// No TracerProvider is installed, so the interceptor's span is a no-op
+	// that carries the remote span context.
+	if _, err := UnaryServerInterceptor()(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/test"}, handler); err != nil {
+		t.Fatalf("UnaryServerInterceptor(...): %v", err)
+	}
+
+	if diff := cmp.Diff("0af7651916cd43dd8448eb211c80319c", got.TraceID().String()); diff != "" {
+		t.Errorf("UnaryServerInterceptor(...): -want trace ID, +got trace ID:\n%s", diff)
+	}
+}
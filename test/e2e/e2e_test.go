@@ -0,0 +1,580 @@
+//go:build linux
+
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	ociv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"golang.org/x/sys/unix"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/crossplane/function-runtime-oci/internal/container"
+	"github.com/crossplane/function-runtime-oci/internal/oci/store"
+	"github.com/crossplane/function-runtime-oci/internal/proto/v1alpha1"
+)
+
+// Functions used by the tests. They're shell scripts that are run using the
+// host's shell.
+var functions = map[string]string{
+	"echo":  "#!/bin/sh\ncat\n",
+	"fail":  "#!/bin/sh\necho boom >&2\nexit 3\n",
+	"env":   "#!/bin/sh\nprintf %s \"$GREETING\"\n",
+	"sleep": "#!/bin/sh\nexec sleep 60\n",
+	"fill":  "#!/bin/sh\nhead -c 33554432 /dev/zero 2>/dev/null >tmp/fill\nsleep 1\nrm -f tmp/fill\nexit 1\n",
+}
+
+// Registry starts an in-memory OCI registry and pushes an image for each test
+// function to it. It returns the registry's host.
+func Registry(t *testing.T) string {
+	t.Helper()
+
+	srv := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	t.Cleanup(srv.Close)
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	for tag, script := range functions {
+		img, err := Image(script)
+		if err != nil {
+			t.Fatalf("cannot build image: %v", err)
+		}
+		ref, err := name.NewTag(host + "/fn:" + tag)
+		if err != nil {
+			t.Fatalf("cannot parse tag: %v", err)
+		}
+		if err := remote.Write(ref, img); err != nil {
+			t.Fatalf("cannot push image: %v", err)
+		}
+	}
+
+	// The multi tag is an index of the echo function for the host's platform,
+	// and the fail function for another platform.
+	idx, err := Index(functions["echo"], functions["fail"])
+	if err != nil {
+		t.Fatalf("cannot build index: %v", err)
+	}
+	ref, err := name.NewTag(host + "/fn:multi")
+	if err != nil {
+		t.Fatalf("cannot parse tag: %v", err)
+	}
+	if err := remote.WriteIndex(ref, idx); err != nil {
+		t.Fatalf("cannot push index: %v", err)
+	}
+	return host
+}
+
+// SlowRegistry starts an OCI registry that never responds. It returns the
+// registry's host.
+func SlowRegistry(t *testing.T) string {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	t.Cleanup(srv.Close)
+	return strings.TrimPrefix(srv.URL, "http://")
+}
+
+// Image returns an OCI image whose entrypoint is the supplied script.
+func Image(script string) (ociv1.Image, error) {
+	b := &bytes.Buffer{}
+	w := tar.NewWriter(b)
+	if err := w.WriteHeader(&tar.Header{Name: "fn", Mode: 0o755, Size: int64(len(script))}); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write([]byte(script)); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	l, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(b.Bytes())), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	img, err := mutate.AppendLayers(empty.Image, l)
+	if err != nil {
+		return nil, err
+	}
+	return mutate.Config(img, ociv1.Config{Entrypoint: []string{"/fn"}})
+}
+
+// Index returns an OCI image index of the host script for the host's platform
+// and the other script for another platform.
+func Index(host, other string) (ociv1.ImageIndex, error) {
+	hi, err := Image(host)
+	if err != nil {
+		return nil, err
+	}
+	oi, err := Image(other)
+	if err != nil {
+		return nil, err
+	}
+	op := ociv1.Platform{OS: runtime.GOOS, Architecture: "arm64"}
+	if runtime.GOARCH == "arm64" {
+		op.Architecture = "amd64"
+	}
+	return mutate.AppendManifests(empty.Index,
+		mutate.IndexAddendum{Add: oi, Descriptor: ociv1.Descriptor{Platform: &op}},
+		mutate.IndexAddendum{Add: hi, Descriptor: ociv1.Descriptor{Platform: &ociv1.Platform{OS: runtime.GOOS, Architecture: runtime.GOARCH}}},
+	), nil
+}
+
+func TestSpark(t *testing.T) {
+	host := Registry(t)
+
+	type want struct {
+		rsp     *v1alpha1.RunFunctionResponse
+		failure *v1alpha1.RunFunctionFailure
+	}
+
+	cases := map[string]struct {
+		reason string
+		req    *v1alpha1.RunFunctionRequest
+		want   want
+	}{
+		"Succeeded": {
+			reason: "spark should write the function's output to stdout.",
+			req:    &v1alpha1.RunFunctionRequest{Image: host + "/fn:echo", Input: []byte("hello")},
+			want:   want{rsp: &v1alpha1.RunFunctionResponse{Output: []byte("hello"), TerminationReason: v1alpha1.TerminationReason_TERMINATION_REASON_EXITED}},
+		},
+		"MultiPlatform": {
+			reason: "spark should run the image for the host's platform from a multi-platform index.",
+			req:    &v1alpha1.RunFunctionRequest{Image: host + "/fn:multi", Input: []byte("hello")},
+			want:   want{rsp: &v1alpha1.RunFunctionResponse{Output: []byte("hello"), TerminationReason: v1alpha1.TerminationReason_TERMINATION_REASON_EXITED}},
+		},
+		"Env": {
+			reason: "spark should set the configured environment variables in the function's container.",
+			req: &v1alpha1.RunFunctionRequest{
+				Image:             host + "/fn:env",
+				RunFunctionConfig: &v1alpha1.RunFunctionConfig{Env: map[string]string{"GREETING": "hello"}},
+			},
+			want: want{rsp: &v1alpha1.RunFunctionResponse{Output: []byte("hello"), TerminationReason: v1alpha1.TerminationReason_TERMINATION_REASON_EXITED}},
+		},
+		"InvalidSecretFile": {
+			reason: "spark should refuse to write a secret file outside its secrets directory.",
+			req: &v1alpha1.RunFunctionRequest{
+				Image:             host + "/fn:echo",
+				RunFunctionConfig: &v1alpha1.RunFunctionConfig{SecretFiles: map[string][]byte{"../token": []byte("secret")}},
+			},
+			want: want{failure: &v1alpha1.RunFunctionFailure{
+				Phase:   v1alpha1.RunFunctionPhase_RUN_FUNCTION_PHASE_SETUP,
+				Message: `invalid RunFunctionConfig: invalid secret file name "../token"`,
+				Code:    int32(codes.InvalidArgument),
+			}},
+		},
+		"InvalidEphemeralStorage": {
+			reason: "spark should refuse to run a function with a non-positive ephemeral storage limit.",
+			req: &v1alpha1.RunFunctionRequest{
+				Image: host + "/fn:echo",
+				RunFunctionConfig: &v1alpha1.RunFunctionConfig{
+					Resources: &v1alpha1.ResourceConfig{Limits: &v1alpha1.ResourceLimits{EphemeralStorage: "0"}},
+				},
+			},
+			want: want{failure: &v1alpha1.RunFunctionFailure{
+				Phase:   v1alpha1.RunFunctionPhase_RUN_FUNCTION_PHASE_SETUP,
+				Message: "invalid RunFunctionConfig: ephemeral storage limit must be greater than zero",
+				Code:    int32(codes.InvalidArgument),
+			}},
+		},
+		"EphemeralStorageExceeded": {
+			reason: "spark should report that a function exceeded its ephemeral storage limit, even if it freed the space before exiting.",
+			req: &v1alpha1.RunFunctionRequest{
+				Image: host + "/fn:fill",
+				RunFunctionConfig: &v1alpha1.RunFunctionConfig{
+					Resources:              &v1alpha1.ResourceConfig{Limits: &v1alpha1.ResourceLimits{EphemeralStorage: "16Mi"}},
+					WritableRootFilesystem: true,
+				},
+			},
+			want: want{failure: &v1alpha1.RunFunctionFailure{
+				Phase:             v1alpha1.RunFunctionPhase_RUN_FUNCTION_PHASE_RUN,
+				Message:           "function exceeded its ephemeral storage limit: container exited with status 1",
+				ExitCode:          1,
+				Code:              int32(codes.ResourceExhausted),
+				TerminationReason: v1alpha1.TerminationReason_TERMINATION_REASON_EXITED,
+			}},
+		},
+		"Failed": {
+			reason: "spark should write a failure describing how the function exited to stdout.",
+			req:    &v1alpha1.RunFunctionRequest{Image: host + "/fn:fail"},
+			want: want{failure: &v1alpha1.RunFunctionFailure{
+				Phase:             v1alpha1.RunFunctionPhase_RUN_FUNCTION_PHASE_RUN,
+				Message:           "OCI runtime error: container exited with status 3",
+				ExitCode:          3,
+				Stderr:            []byte("boom"),
+				Code:              int32(codes.Unknown),
+				TerminationReason: v1alpha1.TerminationReason_TERMINATION_REASON_EXITED,
+			}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			self, err := os.Executable()
+			if err != nil {
+				t.Fatal(err)
+			}
+			in, err := proto.Marshal(tc.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			cmd := exec.Command(self, "spark", "--cache-dir="+t.TempDir(), "--runtime="+self) //nolint:gosec // We're running this test binary as spark.
+			cmd.Stdin, cmd.Stdout, cmd.Stderr = bytes.NewReader(in), stdout, stderr
+			err = cmd.Run()
+
+			got := want{}
+			if err != nil {
+				got.failure = &v1alpha1.RunFunctionFailure{}
+				if err := proto.Unmarshal(stdout.Bytes(), got.failure); err != nil {
+					t.Fatalf("cannot unmarshal failure: %v\nstderr: %s", err, stderr)
+				}
+			} else {
+				got.rsp = &v1alpha1.RunFunctionResponse{}
+				if err := proto.Unmarshal(stdout.Bytes(), got.rsp); err != nil {
+					t.Fatalf("cannot unmarshal response: %v\nstderr: %s", err, stderr)
+				}
+			}
+
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), protocmp.Transform(), protocmp.IgnoreFields(&v1alpha1.RunFunctionFailure{}, "run_id"), protocmp.IgnoreFields(&v1alpha1.RunFunctionResponse{}, "usage")); diff != "" {
+				t.Errorf("\n%s\nspark: -want, +got:\n%s\nstderr: %s", tc.reason, diff, stderr)
+			}
+		})
+	}
+}
+
+func TestSparkCleanup(t *testing.T) {
+	host := Registry(t)
+
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Secret files and an ephemeral storage limit make spark mount tmpfs
+	// filesystems, which it must unmount.
+	cfg := &v1alpha1.RunFunctionConfig{
+		SecretFiles: map[string][]byte{"token": []byte("secret")},
+		Resources:   &v1alpha1.ResourceConfig{Limits: &v1alpha1.ResourceLimits{EphemeralStorage: "16Mi"}},
+	}
+
+	cases := map[string]struct {
+		reason  string
+		timeout time.Duration
+		signal  bool
+	}{
+		"Terminated": {
+			reason: "spark should clean up the run's bundle and mounts when it's terminated mid-run.",
+			signal: true,
+		},
+		"TimedOut": {
+			reason:  "spark should clean up the run's bundle and mounts when the run times out.",
+			timeout: 500 * time.Millisecond,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := proto.Clone(cfg).(*v1alpha1.RunFunctionConfig)
+			if tc.timeout > 0 {
+				cfg.Timeout = durationpb.New(tc.timeout)
+			}
+			in, err := proto.Marshal(&v1alpha1.RunFunctionRequest{Image: host + "/fn:sleep", RunFunctionConfig: cfg})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cache := t.TempDir()
+			stderr := &bytes.Buffer{}
+			cmd := exec.Command(self, "spark", "--cache-dir="+cache, "--runtime="+self) //nolint:gosec // We're running this test binary as spark.
+			cmd.Stdin, cmd.Stderr = bytes.NewReader(in), stderr
+			if err := cmd.Start(); err != nil {
+				t.Fatal(err)
+			}
+
+			// Wait for the function to start.
+			deadline := time.Now().Add(10 * time.Second)
+			for {
+				started, _ := filepath.Glob(filepath.Join(cache, "runtime", "*", fileStarted))
+				if len(started) > 0 {
+					break
+				}
+				if time.Now().After(deadline) {
+					_ = cmd.Process.Kill()
+					t.Fatalf("\n%s\nfunction did not start\nstderr: %s", tc.reason, stderr)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			if tc.signal {
+				if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := cmd.Wait(); err == nil {
+				t.Errorf("\n%s\nspark: want error, got nil", tc.reason)
+			}
+
+			bundles, err := os.ReadDir(filepath.Join(cache, store.DirContainers))
+			if err != nil && !os.IsNotExist(err) {
+				t.Fatal(err)
+			}
+			if len(bundles) > 0 {
+				t.Errorf("\n%s\nspark: want no bundles left in the cache, got %d\nstderr: %s", tc.reason, len(bundles), stderr)
+			}
+			if m := mountsUnder(t, cache); len(m) > 0 {
+				t.Errorf("\n%s\nspark: want no mounts left in the cache, got %v\nstderr: %s", tc.reason, m, stderr)
+			}
+		})
+	}
+}
+
+// mountsUnder returns any mount points under the supplied directory.
+func mountsUnder(t *testing.T, dir string) []string {
+	t.Helper()
+
+	b, err := os.ReadFile("/proc/self/mountinfo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mounts []string
+	for _, line := range strings.Split(string(b), "\n") {
+		// The mount point is the fifth field.
+		fields := strings.Fields(line)
+		if len(fields) > 4 && strings.HasPrefix(fields[4], dir+"/") {
+			mounts = append(mounts, fields[4])
+		}
+	}
+	return mounts
+}
+
+func TestSparkSecretsInMemory(t *testing.T) {
+	host := Registry(t)
+
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Environment variables end up in the bundle's runtime spec.
+	secret := "sup3r-s3cr3t-v4lue"
+	cfg := &v1alpha1.RunFunctionConfig{
+		Env:         map[string]string{"TOKEN": secret},
+		SecretFiles: map[string][]byte{"token": []byte(secret)},
+	}
+	in, err := proto.Marshal(&v1alpha1.RunFunctionRequest{Image: host + "/fn:sleep", RunFunctionConfig: cfg})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache := t.TempDir()
+	stderr := &bytes.Buffer{}
+	cmd := exec.Command(self, "spark", "--cache-dir="+cache, "--runtime="+self) //nolint:gosec // We're running this test binary as spark.
+	cmd.Stdin, cmd.Stderr = bytes.NewReader(in), stderr
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	// Ask spark to stop, rather than killing it, so it cleans up its mounts.
+	defer cmd.Wait()                          //nolint:errcheck // We only care that spark exits.
+	defer cmd.Process.Signal(syscall.SIGTERM) //nolint:errcheck // Ditto.
+
+	// Wait for the function to start, at which point its bundle and secrets
+	// have been written.
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		started, _ := filepath.Glob(filepath.Join(cache, "runtime", "*", fileStarted))
+		if len(started) > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("function did not start\nstderr: %s", stderr)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	found := 0
+	err = filepath.WalkDir(cache, func(path string, d os.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		b, err := os.ReadFile(path) //nolint:gosec // Reading files under the cache dir is the point.
+		if err != nil || !bytes.Contains(b, []byte(secret)) {
+			return err
+		}
+		found++
+		fs := &unix.Statfs_t{}
+		if err := unix.Statfs(path, fs); err != nil {
+			return err
+		}
+		if fs.Type != unix.TMPFS_MAGIC {
+			t.Errorf("spark: want secret values written only to tmpfs, found one on disk in %s", path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The secret file and the runtime spec.
+	if found != 2 {
+		t.Errorf("spark: want secret value in 2 files under the cache dir, found %d", found)
+	}
+}
+
+func TestSparkUnreachableCollector(t *testing.T) {
+	host := Registry(t)
+
+	// A collector that accepts connections but never responds.
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	in, err := proto.Marshal(&v1alpha1.RunFunctionRequest{Image: host + "/fn:echo", Input: []byte("hello")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd := exec.Command(self, "spark", "--cache-dir="+t.TempDir(), "--runtime="+self, "--otlp-endpoint="+lis.Addr().String(), "--otlp-insecure") //nolint:gosec // We're running this test binary as spark.
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = bytes.NewReader(in), stdout, stderr
+
+	started := time.Now()
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("spark: %v\nstderr: %s", err, stderr)
+	}
+
+	// spark should give up flushing spans long before this.
+	if took := time.Since(started); took > 4*time.Second {
+		t.Errorf("spark: want run to finish promptly when the collector is unreachable, took %s", took)
+	}
+
+	got := &v1alpha1.RunFunctionResponse{}
+	if err := proto.Unmarshal(stdout.Bytes(), got); err != nil {
+		t.Fatalf("cannot unmarshal response: %v\nstderr: %s", err, stderr)
+	}
+	if diff := cmp.Diff([]byte("hello"), got.GetOutput()); diff != "" {
+		t.Errorf("spark: -want output, +got output:\n%s", diff)
+	}
+}
+
+func TestRunFunction(t *testing.T) {
+	if err := container.CanCreateUserNamespace(); err != nil {
+		t.Skipf("cannot create user namespaces: %v", err)
+	}
+	host := Registry(t)
+	slow := SlowRegistry(t)
+
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := container.NewRunner(
+		container.WithCacheDir(t.TempDir()),
+		container.WithRuntime(self),
+		container.MapToRoot(os.Getuid(), os.Getgid()),
+	)
+
+	type want struct {
+		rsp  *v1alpha1.RunFunctionResponse
+		code codes.Code
+	}
+
+	cases := map[string]struct {
+		reason string
+		req    *v1alpha1.RunFunctionRequest
+		want   want
+	}{
+		"Succeeded": {
+			reason: "The function's output should be returned.",
+			req:    &v1alpha1.RunFunctionRequest{Image: host + "/fn:echo", Input: []byte("hello")},
+			want:   want{rsp: &v1alpha1.RunFunctionResponse{Output: []byte("hello"), TerminationReason: v1alpha1.TerminationReason_TERMINATION_REASON_EXITED}, code: codes.OK},
+		},
+		"Failed": {
+			reason: "A function that exits unsuccessfully should return an error.",
+			req:    &v1alpha1.RunFunctionRequest{Image: host + "/fn:fail"},
+			want:   want{code: codes.Unknown},
+		},
+		"NotFound": {
+			reason: "A function whose image doesn't exist should return NotFound.",
+			req:    &v1alpha1.RunFunctionRequest{Image: host + "/fn:nope"},
+			want:   want{code: codes.NotFound},
+		},
+		"PullTimedOut": {
+			reason: "A function whose image can't be pulled before the pull timeout should return DeadlineExceeded.",
+			req: &v1alpha1.RunFunctionRequest{
+				Image:             slow + "/fn:echo",
+				RunFunctionConfig: &v1alpha1.RunFunctionConfig{PullTimeout: durationpb.New(100 * time.Millisecond)},
+			},
+			want: want{code: codes.DeadlineExceeded},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			rsp, err := r.RunFunction(context.Background(), tc.req)
+
+			// Usage varies from run to run, so we only check that it's reported.
+			if diff := cmp.Diff(tc.want.rsp, rsp, protocmp.Transform(), protocmp.IgnoreFields(&v1alpha1.RunFunctionResponse{}, "usage")); diff != "" {
+				t.Errorf("\n%s\nRunFunction(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if rsp != nil && rsp.GetUsage().GetWallClock().AsDuration() <= 0 {
+				t.Errorf("\n%s\nRunFunction(...): want usage with a positive wall clock duration, got %v", tc.reason, rsp.GetUsage())
+			}
+			if diff := cmp.Diff(tc.want.code, status.Code(err)); diff != "" {
+				t.Errorf("\n%s\nRunFunction(...): -want code, +got code:\n%s\nerror: %v", tc.reason, diff, err)
+			}
+		})
+	}
+}
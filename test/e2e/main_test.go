@@ -0,0 +1,73 @@
+//go:build linux
+
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package e2e contains end-to-end tests that pull, bundle, and run functions
+// using a fake OCI runtime and an in-memory registry.
+package e2e
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/alecthomas/kong"
+
+	"github.com/crossplane/function-runtime-oci/cmd/function-runtime-oci/spark"
+	"github.com/crossplane/function-runtime-oci/cmd/function-runtime-oci/start"
+)
+
+// TestMain lets this test binary double as spark, and as a fake OCI runtime.
+// The function runner executes its own binary (i.e. this one) as spark, and
+// the tests tell spark to execute this binary as its OCI runtime.
+func TestMain(m *testing.M) {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "spark":
+			os.Exit(runSpark(os.Args[1:]))
+		case "--root":
+			os.Exit(runFakeRuntime(os.Args[2:]))
+		case "--version":
+			// container.CanCreateUserNamespace runs this to check whether it can
+			// create a user namespace.
+			os.Exit(0)
+		}
+	}
+	os.Exit(m.Run())
+}
+
+func runSpark(args []string) int {
+	var cli struct {
+		Registry string        `default:"index.docker.io"`
+		Spark    spark.Command `cmd:""`
+	}
+	p, err := kong.New(&cli)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	ctx, err := p.Parse(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := ctx.Run(&start.Args{Registry: cli.Registry}); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,218 @@
+//go:build linux
+
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	runtime "github.com/opencontainers/runtime-spec/specs-go"
+	"golang.org/x/sys/unix"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+)
+
+// The fake OCI runtime implements enough of the OCI runtime command line
+// interface for spark to run a container. It doesn't create any namespaces or
+// cgroups, and doesn't chroot into the container's rootfs. Instead it executes
+// the container's entrypoint from within the rootfs on the host. Functions
+// run by the fake runtime may therefore use the host's binaries.
+//
+// Container state is stored as JSON under the runtime's root directory.
+
+const (
+	fileState   = "state.json"
+	fileStarted = "started"
+)
+
+// runFakeRuntime runs the fake OCI runtime. The supplied arguments follow the
+// --root flag, i.e. they're the root directory then the command.
+func runFakeRuntime(args []string) int {
+	if len(args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: --root ROOT COMMAND [ARGS]")
+		return 1
+	}
+	root, cmd, args := args[0], args[1], args[2:]
+
+	var err error
+	switch {
+	case cmd == "create" && len(args) == 3 && args[0] == "--bundle":
+		err = fakeCreate(root, args[1], args[2])
+	case cmd == "init" && len(args) == 1:
+		err = fakeInit(root, args[0])
+	case cmd == "start" && len(args) == 1:
+		err = fakeStart(root, args[0])
+	case cmd == "state" && len(args) == 1:
+		err = fakeState(root, args[0])
+	case cmd == "kill" && len(args) == 2:
+		err = fakeKill(root, args[0], args[1])
+	case cmd == "delete" && len(args) == 2 && args[0] == "--force":
+		err = fakeDelete(root, args[1])
+	default:
+		err = errors.Errorf("unsupported command %q", strings.Join(append([]string{cmd}, args...), " "))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// fakeCreate starts a fake init process that waits for the container to be
+// started. The init process inherits our stdio, and outlives us.
+func fakeCreate(root, bundle, id string) error {
+	dir := filepath.Join(root, id)
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return err
+	}
+	if err := os.Mkdir(dir, 0700); err != nil {
+		return errors.Wrap(err, "container already exists")
+	}
+	bundle, err := filepath.Abs(bundle)
+	if err != nil {
+		return err
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(self, "--root", root, "init", id)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	return writeState(dir, &runtime.State{
+		Version: runtime.Version,
+		ID:      id,
+		Status:  runtime.StateCreated,
+		Pid:     cmd.Process.Pid,
+		Bundle:  bundle,
+	})
+}
+
+// fakeInit waits for the container to be started, then replaces itself with
+// the container's entrypoint.
+func fakeInit(root, id string) error {
+	dir := filepath.Join(root, id)
+	for {
+		if _, err := os.Stat(filepath.Join(dir, fileStarted)); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	s, err := readState(dir)
+	if err != nil {
+		return err
+	}
+	b, err := os.ReadFile(filepath.Join(s.Bundle, "config.json"))
+	if err != nil {
+		return err
+	}
+	spec := &runtime.Spec{}
+	if err := json.Unmarshal(b, spec); err != nil {
+		return err
+	}
+	if spec.Root == nil || spec.Process == nil || len(spec.Process.Args) == 0 {
+		return errors.New("config.json must specify a root and process args")
+	}
+
+	rootfs := spec.Root.Path
+	if !filepath.IsAbs(rootfs) {
+		rootfs = filepath.Join(s.Bundle, rootfs)
+	}
+	if err := os.Chdir(filepath.Join(rootfs, spec.Process.Cwd)); err != nil {
+		return err
+	}
+	return syscall.Exec(filepath.Join(rootfs, spec.Process.Args[0]), spec.Process.Args, spec.Process.Env) //nolint:gosec // Executing the function is the point.
+}
+
+func fakeStart(root, id string) error {
+	dir := filepath.Join(root, id)
+	s, err := readState(dir)
+	if err != nil {
+		return err
+	}
+	if s.Status != runtime.StateCreated {
+		return errors.Errorf("cannot start container with status %q", s.Status)
+	}
+	s.Status = runtime.StateRunning
+	if err := writeState(dir, s); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, fileStarted), nil, 0600)
+}
+
+func fakeState(root, id string) error {
+	s, err := readState(filepath.Join(root, id))
+	if err != nil {
+		return err
+	}
+	if s.Status == runtime.StateRunning && unix.Kill(s.Pid, 0) != nil {
+		s.Status = runtime.StateStopped
+	}
+	return json.NewEncoder(os.Stdout).Encode(s)
+}
+
+func fakeKill(root, id, signal string) error {
+	s, err := readState(filepath.Join(root, id))
+	if err != nil {
+		return err
+	}
+	sig := unix.SignalNum("SIG" + strings.TrimPrefix(signal, "SIG"))
+	if sig == 0 {
+		return errors.Errorf("unknown signal %q", signal)
+	}
+	return unix.Kill(s.Pid, sig)
+}
+
+func fakeDelete(root, id string) error {
+	dir := filepath.Join(root, id)
+	s, err := readState(dir)
+	if err != nil {
+		return err
+	}
+	_ = unix.Kill(s.Pid, unix.SIGKILL)
+	return os.RemoveAll(dir)
+}
+
+func readState(dir string) (*runtime.State, error) {
+	b, err := os.ReadFile(filepath.Join(dir, fileState))
+	if err != nil {
+		return nil, errors.Wrap(err, "container does not exist")
+	}
+	s := &runtime.State{}
+	return s, json.Unmarshal(b, s)
+}
+
+func writeState(dir string, s *runtime.State) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, fileState), b, 0600)
+}